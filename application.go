@@ -1,9 +1,10 @@
 package jcli
 
 import (
-	"fmt"
+	"context"
 	"os"
 	"strings"
+	"sync"
 
 	cliflag "github.com/shipengqi/component-base/cli/flag"
 	"github.com/shipengqi/component-base/cli/globalflag"
@@ -13,6 +14,7 @@ import (
 	"github.com/shipengqi/errors"
 	"github.com/shipengqi/log"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -27,6 +29,10 @@ var (
 // RunFunc defines the application's run callback function.
 type RunFunc func() error
 
+// RunContextFunc defines the application's context-aware run callback function.
+// The context is canceled when one of the shutdown signals (SIGINT, SIGTERM) arrives.
+type RunContextFunc func(ctx context.Context) error
+
 // App is the main structure of a cli application.
 type App struct {
 	name             string
@@ -35,10 +41,8 @@ type App struct {
 	examples         string
 	aliases          []string
 	runfunc          RunFunc
-	signalReceiver   SignalReceiver
-	signals          []os.Signal
-	setonce          chan struct{}
-	sigc             chan os.Signal
+	runContextFunc   RunContextFunc
+	signals          *signalRegistry
 	opts             CliOptions
 	logger           Logger
 	flagPrinter      FlagPrinter
@@ -49,13 +53,35 @@ type App struct {
 	hideCompletion   bool
 	subs             []*cobra.Command
 	cmd              *cobra.Command
+	exitCodes        exitCodes
+	hooks            hooks
+	shutdown         *shutdownManager
+	services         []*service
+	recoverer        recoverer
+	middlewares      []Middleware
+	plugins          plugins
+	viper            *viper.Viper
+	envReplacer      *envReplacer
+	configFlag       configFlag
+	layeredConfig    bool
+	strictConfig     bool
+	printSources     bool
+	configPaths      []string
+	configWatcher    *configWatcher
+	enableConfigCmd  bool
+	optsMu           sync.RWMutex
+	configFiles      []string
+	persistentOpts   bool
+	optsFlagSets     cliflag.NamedFlagSets
 }
 
 // New create a new cli application.
 func New(name string, opts ...Option) *App {
 	a := &App{
-		name:    name,
-		setonce: make(chan struct{}),
+		name:       name,
+		signals:    newSignalRegistry(),
+		shutdown:   newShutdownManager(),
+		configFlag: newConfigFlag(),
 	}
 	a.withOptions(opts...)
 
@@ -66,22 +92,85 @@ func New(name string, opts ...Option) *App {
 	if a.flagPrinter == nil {
 		a.flagPrinter = newInfoLogger(a.logger)
 	}
+	if a.viper == nil {
+		a.envReplacer = &envReplacer{}
+		a.viper = viper.NewWithOptions(viper.EnvKeyReplacer(a.envReplacer))
+	}
 
 	a.cmd = a.buildCommand()
 
+	if a.plugins.enabled {
+		if a.plugins.prefix == "" {
+			a.plugins.prefix = NormalizeCliName(a.basename)
+		}
+		a.AddCommands(a.pluginCommand())
+	}
+	if a.enableConfigCmd && !a.disableConfig {
+		a.AddCommands(a.configCommand())
+	}
+
 	return a
 }
 
-// Run is used to launch the application.
+// Run is used to launch the application. It prints the error and exits the
+// process with the resolved exit code when the execution fails.
 func (a *App) Run() {
-	if a.signalReceiver != nil {
-		a.setupSignalHandler(a.signalReceiver, a.signals...)
+	code, err := a.Execute(context.Background())
+	if err != nil {
+		printError(err)
+	}
+	if code != ExitCodeOK {
+		os.Exit(code)
+	}
+}
+
+// Execute launches the application with the given context. Unlike Run, it
+// returns the exit code and the error instead of exiting the process, the
+// exit code is resolved by the ExitError or the registered exit codes.
+//
+// Once any shutdown callbacks are registered, including the ones registered by
+// the run function, the context is canceled on the first shutdown signal, and
+// the callbacks are invoked before Execute returns.
+func (a *App) Execute(ctx context.Context) (int, error) {
+	stopSignals := a.signals.start(a.logger, a.signals.source)
+	defer stopSignals()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if a.plugins.enabled {
+		if len(a.plugins.extensions) > 0 && a.needPluginExtensions(os.Args[1:]) {
+			a.loadPluginExtensions(ctx)
+		}
+		if handled, code, err := a.dispatchPlugin(ctx, os.Args[1:]); handled {
+			return code, err
+		}
 	}
 
-	if err := a.cmd.Execute(); err != nil {
-		fmt.Printf("%v %v\n", Red("Error:"), err)
-		os.Exit(1)
+	stopShutdown := a.shutdown.start(func() func() {
+		return a.watchShutdownSignals(cancel)
+	})
+	defer stopShutdown()
+
+	ctx, finished := withHooksFinished(ctx)
+	err := a.cmd.ExecuteContext(ctx)
+	if !*finished {
+		// the execution finishes before the hooks of the executing command
+		err = a.hooks.finish(ctx, err)
+	}
+	if serr := a.shutdown.run(a.logger); serr != nil {
+		if err != nil {
+			serr = errors.NewAggregate([]error{err, serr})
+		}
+		if errors.Is(serr, ErrShutdownTimeout) {
+			return ExitCodeForcedShutdown, serr
+		}
+		err = serr
 	}
+	if err != nil {
+		return a.exitCodes.code(err), err
+	}
+	return ExitCodeOK, nil
 }
 
 // Command returns cobra command instance inside the App.
@@ -93,13 +182,18 @@ func (a *App) Command() *cobra.Command {
 func (a *App) AddCommands(commands ...*Command) {
 	for _, v := range commands {
 		// Todo force to remove global version flag for the sub commands??
+		v.app = a
 		a.subs = append(a.subs, v.cobraCommand())
 		a.cmd.AddCommand(v.cobraCommand())
 	}
 }
 
-// AddCobraCommands adds multiple sub cobra.Command to the App.
+// AddCobraCommands adds multiple sub cobra.Command to the App. The commands
+// read the configuration of the App before their PreRun and Run.
 func (a *App) AddCobraCommands(commands ...*cobra.Command) {
+	for _, v := range commands {
+		readConfigBeforeRun(v, func() *App { return a })
+	}
 	a.subs = append(a.subs, commands...)
 	a.cmd.AddCommand(commands...)
 }
@@ -109,6 +203,32 @@ func (a *App) Logger() Logger {
 	return a.logger
 }
 
+// Name returns the name of the App.
+func (a *App) Name() string {
+	return a.name
+}
+
+// BaseName returns the basename of the App, it is the name of the root
+// command.
+func (a *App) BaseName() string {
+	return a.basename
+}
+
+// Viper returns the viper instance of the App, which holds the configuration
+// of the App.
+func (a *App) Viper() *viper.Viper {
+	return a.viper
+}
+
+// Options returns the CliOptions of the App, it returns nil if the options
+// are not set. If the config watch is enabled, the options are replaced when
+// the configuration is reloaded.
+func (a *App) Options() CliOptions {
+	a.optsMu.RLock()
+	defer a.optsMu.RUnlock()
+	return a.opts
+}
+
 // withOptions apply options for the application.
 func (a *App) withOptions(opts ...Option) *App {
 	for _, opt := range opts {
@@ -153,7 +273,15 @@ func (a *App) buildCommand() *cobra.Command {
 	// Add command line flag sets
 	if a.opts != nil {
 		nfs = a.opts.Flags()
+		// keep the flag sets of the options, they are shown in the help of
+		// the sub commands if the options are persistent
+		for _, name := range nfs.Order {
+			a.optsFlagSets.FlagSet(name).AddFlagSet(nfs.FlagSets[name])
+		}
 		fs := cmd.Flags()
+		if a.persistentOpts {
+			fs = cmd.PersistentFlags()
+		}
 		for _, set := range nfs.FlagSets {
 			fs.AddFlagSet(set)
 		}
@@ -166,11 +294,18 @@ func (a *App) buildCommand() *cobra.Command {
 	}
 	if !a.disableConfig {
 		a.addConfigFlag(a.basename, nfs.FlagSet(FlagSetNameGlobal))
+		// the config flag is inherited by the sub commands
+		if flag := nfs.FlagSet(FlagSetNameGlobal).Lookup(a.configFlag.name); flag != nil {
+			cmd.PersistentFlags().AddFlag(flag)
+		}
+		nfs.FlagSet(FlagSetNameGlobal).BoolVar(&a.printSources, showConfigSourcesFlagName, false,
+			"Print the value and the source of each configuration key and quit.")
+		cmd.Flags().AddFlag(nfs.FlagSet(FlagSetNameGlobal).Lookup(showConfigSourcesFlagName))
 	}
 	globalflag.AddGlobalFlags(nfs.FlagSet(FlagSetNameGlobal), cmd.Name())
 
 	width, _, _ := term.TerminalSize(cmd.OutOrStdout())
-	cliflag.SetUsageAndHelpFunc(cmd, nfs, width)
+	a.setUsageAndHelpFunc(cmd, nfs, width)
 
 	return cmd
 }
@@ -179,20 +314,39 @@ func (a *App) run(cmd *cobra.Command, args []string) error {
 	if !a.disableVersion {
 		verflag.PrintAndExitIfRequested()
 	}
+	if !a.disableConfig {
+		if err := a.readConfigFile(); err != nil {
+			return err
+		}
+	}
+	if a.printSources {
+		return a.printConfigSources(cmd.OutOrStdout())
+	}
 
+	ctx := cmd.Context()
+	return a.hooks.execute(ctx, []*hooks{&a.hooks}, args,
+		func(fn func() error) error {
+			return a.recoverer.call(cmd, fn)
+		},
+		func() error {
+			return a.prepare(cmd, args)
+		},
+		func() error {
+			return a.runE(ctx)
+		},
+	)
+}
+
+// prepare prints the startup information, reads the configuration and applies
+// the options before the run callback.
+func (a *App) prepare(cmd *cobra.Command, args []string) error {
 	if !a.silence {
 		a.PrintWorkingDir()
 		cliflag.PrintFlags(cmd.Flags(), a.flagPrinter)
 	}
 
-	if !a.disableConfig && a.opts != nil {
-		if err := viper.BindPFlags(cmd.Flags()); err != nil {
-			return err
-		}
-
-		if err := viper.Unmarshal(a.opts); err != nil {
-			return err
-		}
+	if err := a.loadOptions(cmd.Flags()); err != nil {
+		return err
 	}
 
 	if !a.silence {
@@ -201,8 +355,8 @@ func (a *App) run(cmd *cobra.Command, args []string) error {
 		if !a.disableVersion {
 			a.logger.Infof("%s Version: \n%s", progressMessage, version.Get().String())
 		}
-		if !a.disableConfig && viper.ConfigFileUsed() != "" {
-			a.logger.Infof("%s Config file used: `%s`", progressMessage, viper.ConfigFileUsed())
+		if files := a.configFilesUsed(); !a.disableConfig && len(files) > 0 {
+			a.logger.Infof("%s Config file used: `%s`", progressMessage, strings.Join(files, "`, `"))
 		}
 	}
 
@@ -212,22 +366,68 @@ func (a *App) run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if a.runfunc != nil {
-		return a.runfunc()
+	return nil
+}
+
+// loadOptions binds the flags and unmarshals the configuration into the
+// options.
+func (a *App) loadOptions(fs *pflag.FlagSet) error {
+	if a.disableConfig || a.opts == nil {
+		return nil
+	}
+	if err := a.checkConfigKeys(); err != nil {
+		return err
 	}
+	if err := a.viper.BindPFlags(fs); err != nil {
+		return err
+	}
+	return a.viper.Unmarshal(a.opts)
+}
 
-	return nil
+// prepareOptions loads and applies the persistent options before a sub
+// command runs.
+func (a *App) prepareOptions(fs *pflag.FlagSet) error {
+	if err := a.loadOptions(fs); err != nil {
+		return err
+	}
+	return a.applyOptions()
 }
 
-func (a *App) applyOptions() error {
-	if options, ok := a.opts.(CompletableOptions); ok {
-		if err := options.Complete(); err != nil {
-			return err
+func (a *App) runE(ctx context.Context) error {
+	if a.configWatcher != nil && !a.disableConfig && a.opts != nil {
+		stop := a.watchConfig()
+		defer stop()
+	}
+
+	if a.runContextFunc == nil && len(a.services) == 0 {
+		if a.runfunc != nil {
+			return a.runfunc()
 		}
+		return nil
 	}
 
-	if errs := a.opts.Validate(); len(errs) != 0 {
-		return errors.NewAggregate(errs)
+	ctx, cancel := a.signals.notifyContext(ctx)
+	defer cancel()
+
+	if len(a.services) == 0 {
+		return a.runContextFunc(ctx)
+	}
+
+	// the run callback is supervised along with the services
+	services := a.services
+	if a.runContextFunc != nil {
+		services = append([]*service{{name: a.name, start: ServiceFunc(a.runContextFunc)}}, services...)
+	} else if a.runfunc != nil {
+		services = append([]*service{{name: a.name, start: func(context.Context) error {
+			return a.runfunc()
+		}}}, services...)
+	}
+	return a.runServices(ctx, services)
+}
+
+func (a *App) applyOptions() error {
+	if err := completeAndValidate(a.opts); err != nil {
+		return err
 	}
 
 	if options, ok := a.opts.(PrintableOptions); ok && !a.silence {
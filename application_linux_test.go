@@ -2,10 +2,10 @@ package jcli_test
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"syscall"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -13,68 +13,48 @@ import (
 )
 
 func TestAppSignalReceiver(t *testing.T) {
-	t.Run("with default signals", func(t *testing.T) {
+	execute := func(t *testing.T, sig os.Signal, signals ...os.Signal) string {
 		os.Args = []string{"testApp"}
 		var buf bytes.Buffer
 		log := newTestLogger(&buf)
-		var pid int
+		injector := jcli.NewSignalInjector()
+		running := make(chan struct{})
+		received := make(chan struct{})
 		app := jcli.New("simple",
 			jcli.WithCliOptions(&fakeCliOptions{"Pooky", "PASS"}),
 			jcli.WithBaseName("testApp"),
 			jcli.WithLogger(log),
+			jcli.WithSignalSource(injector),
 			jcli.DisableConfig(),
 			jcli.DisableVersion(),
 			jcli.WithRunFunc(func() error {
 				log.Infof("application running")
-				time.Sleep(time.Second * 2)
+				close(running)
+				<-received
 				return nil
 			}),
 			jcli.WithOnSignalReceived(func(signal os.Signal) {
 				log.Infof("signal: %s", signal.String())
-			}),
+			}, signals...),
 		)
 		go func() {
-			pid = syscall.Getpid()
-			app.Run()
-		}()
-		time.Sleep(time.Millisecond * 500)
-		go func() {
-			syscall.Kill(pid, syscall.SIGINT)
+			<-running
+			assert.True(t, injector.Send(sig))
+			close(received)
 		}()
-		time.Sleep(time.Second * 5)
-		assert.Contains(t, buf.String(), "[info] application running")
-		assert.Contains(t, buf.String(), "signal: interrupt")
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		return buf.String()
+	}
+
+	t.Run("with default signals", func(t *testing.T) {
+		output := execute(t, syscall.SIGINT)
+		assert.Contains(t, output, "[info] application running")
+		assert.Contains(t, output, "signal: interrupt")
 	})
 	t.Run("with custom signals", func(t *testing.T) {
-		os.Args = []string{"testApp"}
-		var buf bytes.Buffer
-		log := newTestLogger(&buf)
-		var pid int
-		app := jcli.New("simple",
-			jcli.WithCliOptions(&fakeCliOptions{"Pooky", "PASS"}),
-			jcli.WithBaseName("testApp"),
-			jcli.WithLogger(log),
-			jcli.DisableConfig(),
-			jcli.DisableVersion(),
-			jcli.WithRunFunc(func() error {
-				log.Infof("application running")
-				time.Sleep(time.Second * 2)
-				return nil
-			}),
-			jcli.WithOnSignalReceived(func(signal os.Signal) {
-				log.Infof("signal: %s", signal.String())
-			}, syscall.SIGUSR1),
-		)
-		go func() {
-			pid = syscall.Getpid()
-			app.Run()
-		}()
-		time.Sleep(time.Millisecond * 500)
-		go func() {
-			syscall.Kill(pid, syscall.SIGUSR1)
-		}()
-		time.Sleep(time.Second * 5)
-		assert.Contains(t, buf.String(), "[info] application running")
-		assert.Contains(t, buf.String(), "signal: user defined signal 1")
+		output := execute(t, syscall.SIGUSR1, syscall.SIGUSR1)
+		assert.Contains(t, output, "[info] application running")
+		assert.Contains(t, output, "signal: user defined signal 1")
 	})
 }
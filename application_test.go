@@ -2,6 +2,7 @@ package jcli_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -217,6 +218,30 @@ func TestAppRun(t *testing.T) {
 		assert.NotContains(t, string(stdout), "--version version[=true]")
 		assert.NotContains(t, string(stdout), "-c, --config FILE")
 	})
+	t.Run("with run context", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		log := newTestLogger(&buf)
+		app := jcli.New("simple",
+			jcli.WithCliOptions(&fakeCliOptions{"Pooky", "PASS"}),
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(log),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithRunFunc(func() error {
+				log.Infof("application running")
+				return nil
+			}),
+			jcli.WithRunContextFunc(func(ctx context.Context) error {
+				assert.NotNil(t, ctx)
+				assert.NoError(t, ctx.Err())
+				log.Infof("application running with context")
+				return nil
+			}))
+		app.Run()
+		assert.Contains(t, buf.String(), "[info] application running with context")
+		assert.NotContains(t, buf.String(), "[info] application running\n")
+	})
 	t.Run("without run", func(t *testing.T) {
 		os.Args = []string{"testApp"}
 		r, w, _ := os.Pipe()
@@ -431,4 +456,125 @@ func TestAppRun(t *testing.T) {
 		app.Run()
 		assert.Contains(t, buf.String(), "sub1-sub1 command running")
 	})
+
+	t.Run("sub commands should share the App", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub1", "sub1-sub1"}
+		var buf bytes.Buffer
+		log := newTestLogger(&buf)
+		opts := &fakeCliOptions{"Pooky", "PASS"}
+		app := jcli.New("simple",
+			jcli.WithCliOptions(opts),
+			jcli.WithLogger(log),
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+		)
+		assert.Equal(t, "simple", app.Name())
+		assert.Equal(t, "testApp", app.BaseName())
+		assert.Equal(t, opts, app.Options())
+
+		sub1 := jcli.NewCommand("sub1", "sub1 command description")
+		sub1.AddCommands(
+			jcli.NewCommand("sub1-sub1", "sub1-sub1 command description",
+				jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+					assert.Equal(t, app, cmd.App())
+					assert.Equal(t, opts, cmd.RootOptions())
+					assert.Equal(t, "testApp sub1 sub1-sub1", cmd.CommandPath())
+					assert.Equal(t, os.Stdout, cmd.IOStreams().Out)
+					cmd.Logger().Infof("sub1-sub1 command running")
+					return nil
+				}),
+			),
+		)
+		app.AddCommands(sub1)
+
+		code, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.Contains(t, buf.String(), "[info] sub1-sub1 command running")
+	})
+}
+
+type completableCliOptions struct {
+	fakeCliOptions `mapstructure:",squash"`
+	completed      bool
+}
+
+func (o *completableCliOptions) Complete() error {
+	o.completed = true
+	return nil
+}
+
+func (o *completableCliOptions) Validate() []error {
+	if o.Username == "" {
+		return []error{fmt.Errorf("username is required")}
+	}
+	return nil
+}
+
+func TestAppPersistentCliOptions(t *testing.T) {
+	newApp := func(opts jcli.CliOptions, run jcli.RunCommandFunc) *jcli.App {
+		app := jcli.New("simple",
+			jcli.WithCliOptions(opts),
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(newTestLogger(&bytes.Buffer{})),
+			jcli.EnablePersistentCliOptions(),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		sub1 := jcli.NewCommand("sub1", "sub1 command description")
+		sub1.AddCommands(jcli.NewCommand("sub1-sub1", "sub1-sub1 command description",
+			jcli.WithCommandRunFunc(run)))
+		app.AddCommands(sub1)
+		return app
+	}
+
+	t.Run("sub commands should inherit the options", func(t *testing.T) {
+		t.Setenv("TESTAPP_PASSWORD", "env-pass")
+		os.Args = []string{"testApp", "sub1", "sub1-sub1", "--username", "x"}
+		opts := &completableCliOptions{}
+		var ran bool
+		app := newApp(opts, func(cmd *jcli.Command, args []string) error {
+			ran = true
+			root, ok := cmd.RootOptions().(*completableCliOptions)
+			assert.True(t, ok)
+			assert.Equal(t, "x", root.Username)
+			assert.Equal(t, "env-pass", root.Password)
+			assert.True(t, root.completed)
+			return nil
+		})
+		code, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.True(t, ran)
+	})
+
+	t.Run("should validate the options before sub commands run", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub1", "sub1-sub1"}
+		var ran bool
+		app := newApp(&completableCliOptions{}, func(cmd *jcli.Command, args []string) error {
+			ran = true
+			return nil
+		})
+		_, err := app.Execute(context.Background())
+		assert.ErrorContains(t, err, "username is required")
+		assert.False(t, ran)
+	})
+
+	t.Run("help message of sub commands should contain the options", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub1", "sub1-sub1", "--help"}
+		r, w, _ := os.Pipe()
+		tmp := os.Stdout
+		defer func() {
+			os.Stdout = tmp
+		}()
+		os.Stdout = w
+		app := newApp(&completableCliOptions{}, nil)
+		_, err := app.Execute(context.Background())
+		_ = w.Close()
+		stdout, _ := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Contains(t, string(stdout), "Fake flags:")
+		assert.Contains(t, string(stdout), "--username")
+	})
 }
@@ -1,13 +1,15 @@
 package jcli
 
 import (
-	"fmt"
+	"context"
+	"io"
 	"os"
 
 	cliflag "github.com/shipengqi/component-base/cli/flag"
 	"github.com/shipengqi/component-base/term"
 	"github.com/shipengqi/component-base/version/verflag"
 	"github.com/shipengqi/errors"
+	"github.com/shipengqi/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -15,6 +17,21 @@ import (
 // RunCommandFunc defines the command startup callback function.
 type RunCommandFunc func(cmd *Command, args []string) error
 
+// RunCommandContextFunc defines the command's context-aware startup callback
+// function. The context is canceled when one of the shutdown signals (SIGINT,
+// SIGTERM) arrives.
+type RunCommandContextFunc func(ctx context.Context, cmd *Command, args []string) error
+
+// IOStreams holds the standard streams of a Command.
+type IOStreams struct {
+	// In is the standard input stream.
+	In io.Reader
+	// Out is the standard output stream.
+	Out io.Writer
+	// ErrOut is the standard error stream.
+	ErrOut io.Writer
+}
+
 // Command is a sub command structure of a cli application.
 // It is recommended that a command be created with the app.NewCommand()
 // function.
@@ -31,14 +48,24 @@ type Command struct {
 	subs             []*cobra.Command
 	cmd              *cobra.Command
 	runfunc          RunCommandFunc
+	runContextFunc   RunCommandContextFunc
+	exitCodes        exitCodes
+	hooks            hooks
+	signals          *signalRegistry
+	recoverer        recoverer
+	middlewares      []Middleware
+	builtin          bool
+	parent           *Command
+	app              *App
 }
 
 // NewCommand creates a new sub command instance based on the given command name
 // and other options.
 func NewCommand(name string, short string, opts ...CommandOption) *Command {
 	c := &Command{
-		name:  name,
-		short: short,
+		name:    name,
+		short:   short,
+		signals: newSignalRegistry(),
 	}
 	c.withOptions(opts...)
 
@@ -50,13 +77,18 @@ func NewCommand(name string, short string, opts ...CommandOption) *Command {
 func (c *Command) AddCommands(commands ...*Command) {
 	for _, v := range commands {
 		// Todo force to remove global version flag for the sub commands
+		v.parent = c
 		c.subs = append(c.subs, v.cobraCommand())
 		c.cmd.AddCommand(v.cobraCommand())
 	}
 }
 
 // AddCobraCommands adds multiple sub cobra.Command to the current command.
+// The commands read the configuration of the App before their PreRun and Run.
 func (c *Command) AddCobraCommands(commands ...*cobra.Command) {
+	for _, v := range commands {
+		readConfigBeforeRun(v, c.App)
+	}
 	c.subs = append(c.subs, commands...)
 	c.cmd.AddCommand(commands...)
 }
@@ -101,15 +133,95 @@ func (c *Command) MarkHidden(flags ...string) {
 	}
 }
 
-// Run runs the command.
+// App returns the App which the root of the Command is added to, it returns
+// nil if the Command is separated from the application.
+func (c *Command) App() *App {
+	return c.root().app
+}
+
+// Logger returns the logger of the App, or the default logger if the Command
+// is separated from the application.
+func (c *Command) Logger() Logger {
+	if app := c.App(); app != nil {
+		return app.logger
+	}
+	return log.WithValues()
+}
+
+// RootOptions returns the CliOptions of the App, or the CliOptions of the root
+// Command if the Command is separated from the application.
+func (c *Command) RootOptions() CliOptions {
+	if app := c.App(); app != nil {
+		return app.Options()
+	}
+	return c.root().opts
+}
+
+// IOStreams returns the standard streams of the Command.
+func (c *Command) IOStreams() IOStreams {
+	return IOStreams{
+		In:     c.cmd.InOrStdin(),
+		Out:    c.cmd.OutOrStdout(),
+		ErrOut: c.cmd.ErrOrStderr(),
+	}
+}
+
+// CommandPath returns the full path of the Command, e.g. "demo sub1 sub2".
+func (c *Command) CommandPath() string {
+	if c.cmd == nil {
+		return ""
+	}
+	return c.cmd.CommandPath()
+}
+
+// isBuiltin reports whether the Command is a built-in command of the App, e.g.
+// "config" and "plugin", which does not require the persistent options.
+func (c *Command) isBuiltin() bool {
+	for current := c; current != nil; current = current.parent {
+		if current.builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// root returns the root Command of the current command.
+func (c *Command) root() *Command {
+	current := c
+	for current.parent != nil {
+		current = current.parent
+	}
+	return current
+}
+
+// Run runs the command. It prints the error and exits the process with the
+// resolved exit code when the execution fails.
 func (c *Command) Run() {
+	code, err := c.Execute(context.Background())
+	if err != nil {
+		printError(err)
+	}
+	if code != ExitCodeOK {
+		os.Exit(code)
+	}
+}
+
+// Execute runs the command with the given context. Unlike Run, it returns the
+// exit code and the error instead of exiting the process.
+func (c *Command) Execute(ctx context.Context) (int, error) {
 	if c.cmd == nil {
-		return
+		return ExitCodeOK, nil
 	}
-	if err := c.cmd.Execute(); err != nil {
-		fmt.Printf("%v %v\n", Red("Error:"), err)
-		os.Exit(1)
+	ctx, finished := withHooksFinished(ctx)
+	err := c.cmd.ExecuteContext(ctx)
+	if !*finished {
+		// the execution finishes before the hooks of the executing command
+		err = c.hooks.finish(ctx, err)
 	}
+	if err != nil {
+		return c.exitCodes.code(err), err
+	}
+	return ExitCodeOK, nil
 }
 
 func (c *Command) cobraCommand() *cobra.Command {
@@ -164,19 +276,110 @@ func (c *Command) cobraCommand() *cobra.Command {
 	}
 
 	width, _, _ := term.TerminalSize(cmd.OutOrStdout())
-	cliflag.SetUsageAndHelpFunc(cmd, nfs, width)
+	c.setUsageAndHelpFunc(cmd, nfs, width)
 	return cmd
 }
 
-func (c *Command) run(_ *cobra.Command, args []string) error {
+func (c *Command) run(cmd *cobra.Command, args []string) error {
 	if c.enableVersion {
 		verflag.PrintAndExitIfRequested()
 	}
 
-	if c.opts != nil {
-		if err := c.applyOptions(); err != nil {
-			return err
+	stopSignals := c.startSignals()
+	defer stopSignals()
+
+	ctx := cmd.Context()
+	recoverer := c.recovererChain()
+	return c.hooks.execute(ctx, c.hookChain(), args,
+		func(fn func() error) error {
+			return recoverer.call(cmd, fn)
+		},
+		func() error {
+			app := c.App()
+			if app != nil && !app.disableConfig {
+				if err := app.readConfigFile(); err != nil {
+					return err
+				}
+			}
+			// the persistent options of the App are applied before the
+			// options of the Command
+			if app != nil && app.persistentOpts && app.opts != nil && !c.isBuiltin() {
+				if err := app.prepareOptions(app.cmd.PersistentFlags()); err != nil {
+					return err
+				}
+			}
+			if c.opts == nil {
+				return nil
+			}
+			if err := c.loadConfig(cmd); err != nil {
+				return err
+			}
+			return c.applyOptions()
+		},
+		func() error {
+			return c.runWithMiddlewares(ctx, args)
+		},
+	)
+}
+
+// startSignals starts relaying the signals to the handlers of the Command and
+// its parent commands, so the handlers of the parents are active while the
+// Command is running.
+func (c *Command) startSignals() (stop func()) {
+	var stops []func()
+	for current := c; current != nil; current = current.parent {
+		stops = append(stops, current.signals.start(c.Logger(), current.signalSource()))
+	}
+	return func() {
+		for _, v := range stops {
+			v()
+		}
+	}
+}
+
+// signalSource returns the SignalSource of the Command. If the source is not
+// set on the Command, it is inherited from the parent commands and the App.
+func (c *Command) signalSource() SignalSource {
+	current := c
+	for {
+		if current.signals.source != nil {
+			return current.signals.source
+		}
+		if current.parent == nil {
+			break
+		}
+		current = current.parent
+	}
+	if current.app != nil {
+		return current.app.signals.source
+	}
+	return nil
+}
+
+// recovererChain returns the recoverer of the Command. If the recovery is not
+// enabled on the Command, it is inherited from the parent commands and the App.
+func (c *Command) recovererChain() *recoverer {
+	current := c
+	for {
+		if current.recoverer.enabled {
+			return &current.recoverer
+		}
+		if current.parent == nil {
+			break
 		}
+		current = current.parent
+	}
+	if current.app != nil && current.app.recoverer.enabled {
+		return &current.app.recoverer
+	}
+	return &c.recoverer
+}
+
+func (c *Command) runE(ctx context.Context, args []string) error {
+	if c.runContextFunc != nil {
+		ctx, cancel := c.signals.notifyContext(ctx)
+		defer cancel()
+		return c.runContextFunc(ctx, c, args)
 	}
 	if c.runfunc != nil {
 		if err := c.runfunc(c, args); err != nil {
@@ -186,6 +389,24 @@ func (c *Command) run(_ *cobra.Command, args []string) error {
 	return nil
 }
 
+// hookChain returns the hooks from the root App or Command down to the
+// current command.
+func (c *Command) hookChain() []*hooks {
+	var chain []*hooks
+	current := c
+	for {
+		chain = append([]*hooks{&current.hooks}, chain...)
+		if current.parent == nil {
+			break
+		}
+		current = current.parent
+	}
+	if current.app != nil {
+		chain = append([]*hooks{&current.app.hooks}, chain...)
+	}
+	return chain
+}
+
 // withOptions apply options for the application.
 func (c *Command) withOptions(opts ...CommandOption) *Command {
 	for _, opt := range opts {
@@ -2,6 +2,7 @@ package jcli_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -177,4 +178,44 @@ func TestCommandRun(t *testing.T) {
 		assert.NotContains(t, string(stdout), "--version version[=true]")
 		assert.NotContains(t, string(stdout), "-c, --config FILE")
 	})
+
+	t.Run("with run context", func(t *testing.T) {
+		os.Args = []string{"simplecmd", "arg1"}
+		var buf bytes.Buffer
+		log := newTestLogger(&buf)
+		app := jcli.NewCommand("simplecmd", "this is a test command",
+			jcli.WithCommandCliOptions(&fakeCliOptions{"Pooky", "PASS"}),
+			jcli.WithCommandRunContextFunc(func(ctx context.Context, cmd *jcli.Command, args []string) error {
+				assert.NotNil(t, ctx)
+				assert.NoError(t, ctx.Err())
+				log.Infof("command running with context: %v", args)
+				return nil
+			}),
+		)
+		app.Run()
+		assert.Contains(t, buf.String(), "[info] command running with context: [arg1]")
+	})
+
+	t.Run("separated command should resolve its own setup", func(t *testing.T) {
+		os.Args = []string{"simplecmd", "sub1"}
+		opts := &fakeCliOptions{"Pooky", "PASS"}
+		var ran bool
+		cmd := jcli.NewCommand("simplecmd", "this is a test command",
+			jcli.WithCommandCliOptions(opts),
+		)
+		cmd.AddCommands(jcli.NewCommand("sub1", "sub1 command",
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				ran = true
+				assert.Nil(t, cmd.App())
+				assert.NotNil(t, cmd.Logger())
+				assert.Equal(t, opts, cmd.RootOptions())
+				assert.Equal(t, "simplecmd sub1", cmd.CommandPath())
+				return nil
+			}),
+		))
+		code, err := cmd.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.True(t, ran)
+	})
 }
@@ -2,6 +2,7 @@ package jcli
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,47 +13,189 @@ import (
 	"github.com/spf13/viper"
 )
 
-const ConfigFlagName = "config"
+const (
+	// ConfigFlagName is the default name of the config flag.
+	ConfigFlagName = "config"
+	// ConfigFlagShorthand is the default shorthand of the config flag.
+	ConfigFlagShorthand = "c"
 
-var _filename string
+	defaultConfigFlagUsage = "Read configuration from specified `FILE`, " +
+		"support JSON, TOML, YAML, HCL, or Java properties formats."
+)
+
+// configFlag holds the config flag of an App and the config file specified by
+// the flag.
+type configFlag struct {
+	name      string
+	shorthand string
+	usage     string
+	disabled  bool
+	filename  string
+}
 
-func init() {
-	pflag.StringVarP(&_filename, ConfigFlagName, "c", _filename,
-		"Read configuration from specified `FILE`, support JSON, TOML, YAML, HCL, or Java properties formats.")
+func newConfigFlag() configFlag {
+	return configFlag{
+		name:      ConfigFlagName,
+		shorthand: ConfigFlagShorthand,
+		usage:     defaultConfigFlagUsage,
+	}
 }
 
 // addConfigFlag adds flags for a specific server to the specified FlagSet
 // object.
 func (a *App) addConfigFlag(basename string, fs *pflag.FlagSet) {
-	fs.AddFlag(pflag.Lookup(ConfigFlagName))
+	if !a.configFlag.disabled {
+		fs.StringVarP(&a.configFlag.filename, a.configFlag.name, a.configFlag.shorthand,
+			a.configFlag.filename, a.configFlag.usage)
+	}
 
-	viper.AutomaticEnv()
-	viper.SetEnvPrefix(strings.ReplaceAll(strings.ToUpper(basename), "-", "_"))
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	a.viper.AutomaticEnv()
+	a.viper.SetEnvPrefix(envPrefix(basename))
+	replacer := a.envReplacer
+	if replacer == nil {
+		// the viper set by WithViper checks the derived variables first
+		replacer = &envReplacer{}
+		a.viper.SetEnvKeyReplacer(envKeyReplacer)
+	}
+	_ = replacer.bindFlags(a.viper, envPrefix(basename), a.optionsFlagSet())
+}
 
-	cobra.OnInitialize(func() {
-		if _filename != "" {
-			viper.SetConfigFile(_filename)
-		} else {
-			viper.AddConfigPath(".")
+// readConfigFile reads the configuration file of the App. It is called once
+// the flags of the executing command are parsed, so the config flag is
+// resolved, see App.run and Command.run.
+func (a *App) readConfigFile() error {
+	if err := a.readConfig(a.basename); err != nil {
+		return fmt.Errorf("failed to read configuration file(%s): %w", a.configFlag.filename, err)
+	}
+	return nil
+}
 
-			if names := strings.Split(basename, "-"); len(names) > 1 {
-				viper.AddConfigPath(filepath.Join(sysutil.HomeDir(), "."+names[0]))
-				viper.AddConfigPath(filepath.Join("/etc", names[0]))
-			}
+// readConfig reads the configuration file specified by the config flag, or
+// searches it in the config paths. It is not an error if no configuration
+// file is found in the config paths.
+func (a *App) readConfig(basename string) error {
+	if a.layeredConfig {
+		return a.readLayeredConfig(basename)
+	}
+	if a.configFlag.filename != "" {
+		a.viper.SetConfigFile(a.configFlag.filename)
+	} else {
+		for _, path := range a.configSearchPaths(basename) {
+			a.viper.AddConfigPath(path)
+		}
+		a.viper.SetConfigName(basename)
+	}
 
-			viper.SetConfigName(basename)
+	if err := a.viper.ReadInConfig(); err != nil {
+		var configFileNotFoundError viper.ConfigFileNotFoundError
+		// cannot find any configuration files in the search paths
+		if a.configFlag.filename == "" && errors.As(err, &configFileNotFoundError) {
+			return nil
 		}
+		return err
+	}
+	return nil
+}
 
-		if err := viper.ReadInConfig(); err != nil {
-			var configFileNotFoundError viper.ConfigFileNotFoundError
-			// cannot find any configuration files in the /home and /etc directories
-			if _filename == "" && errors.As(err, &configFileNotFoundError) {
-				return
+// readConfigBeforeRun makes the cobra command and its sub commands read the
+// configuration of the App returned by app before their PreRun and Run.
+func readConfigBeforeRun(cmd *cobra.Command, app func() *App) {
+	preRunE, preRun := cmd.PreRunE, cmd.PreRun
+	cmd.PreRun = nil
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if a := app(); a != nil && !a.disableConfig {
+			if err := a.readConfigFile(); err != nil {
+				return err
 			}
-			a.logger.Fatalf("Error: failed to read configuration file(%s): %v\n", _filename, err)
 		}
-	})
+		if preRunE != nil {
+			return preRunE(cmd, args)
+		}
+		if preRun != nil {
+			preRun(cmd, args)
+		}
+		return nil
+	}
+	for _, sub := range cmd.Commands() {
+		readConfigBeforeRun(sub, app)
+	}
+}
+
+// loadConfig unmarshals the options of the Command from the section of the
+// configuration named by the command path, e.g. "sub1" for "demo sub1", and the
+// environment variables prefixed with the command path, e.g. DEMO_SUB1_USERNAME.
+// The flags of the Command take precedence.
+func (c *Command) loadConfig(cmd *cobra.Command) error {
+	app := c.App()
+	if app == nil || app.disableConfig || c.opts == nil {
+		return nil
+	}
+
+	path := strings.Fields(cmd.CommandPath())[1:]
+	replacer := &envReplacer{}
+	v := viper.NewWithOptions(viper.EnvKeyReplacer(replacer))
+	if section := app.viper.Sub(strings.Join(path, ".")); section != nil {
+		if err := v.MergeConfigMap(section.AllSettings()); err != nil {
+			return err
+		}
+	}
+	prefix := commandEnvPrefix(app.basename, cmd)
+	v.AutomaticEnv()
+	v.SetEnvPrefix(prefix)
+	if err := replacer.bindFlags(v, prefix, cmd.LocalFlags()); err != nil {
+		return err
+	}
+	if err := v.BindPFlags(cmd.LocalFlags()); err != nil {
+		return err
+	}
+	return v.Unmarshal(c.opts)
+}
+
+// configFilesUsed returns the configuration files used by the App.
+func (a *App) configFilesUsed() []string {
+	if a.layeredConfig {
+		return a.configFiles
+	}
+	if file := a.viper.ConfigFileUsed(); file != "" {
+		return []string{file}
+	}
+	return nil
+}
+
+// configSearchPaths returns the directories to search the configuration file
+// in, the first one has the highest precedence. It defaults to the working
+// directory, $XDG_CONFIG_HOME/<basename>, ~/.<prefix>,
+// $XDG_CONFIG_DIRS/<basename> and /etc/<prefix>, where the prefix is the first
+// word of the basename, e.g. "demo" for "demo-server".
+func (a *App) configSearchPaths(basename string) []string {
+	if len(a.configPaths) > 0 {
+		return a.configPaths
+	}
+
+	prefix := strings.Split(basename, "-")[0]
+	home := sysutil.HomeDir()
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	configDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if configDirs == "" {
+		configDirs = "/etc/xdg"
+	}
+
+	paths := []string{".", filepath.Join(configHome, basename), filepath.Join(home, "."+prefix)}
+	for _, dir := range filepath.SplitList(configDirs) {
+		if dir != "" {
+			paths = append(paths, filepath.Join(dir, basename))
+		}
+	}
+	return append(paths, filepath.Join("/etc", prefix))
+}
+
+// envPrefix returns the prefix of the environment variables of the given
+// basename, e.g. "DEMO_SERVER" for "demo-server".
+func envPrefix(basename string) string {
+	return strings.ReplaceAll(strings.ToUpper(basename), "-", "_")
 }
 
 func (a *App) PrintWorkingDir() {
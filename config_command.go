@@ -0,0 +1,264 @@
+package jcli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+const configCommandName = "config"
+
+// configViewOptions is the CliOptions of the "config view" command.
+type configViewOptions struct {
+	Output string
+}
+
+func (o *configViewOptions) Flags() (fss cliflag.NamedFlagSets) {
+	fs := fss.FlagSet("view")
+	fs.StringVarP(&o.Output, "output", "o", "yaml", "Output format, one of yaml and json.")
+	return fss
+}
+
+func (o *configViewOptions) Validate() []error {
+	if o.Output != "yaml" && o.Output != "json" {
+		return []error{fmt.Errorf("unsupported output format %q, must be one of yaml and json", o.Output)}
+	}
+	return nil
+}
+
+// configInitOptions is the CliOptions of the "config init" command.
+type configInitOptions struct {
+	Format string
+	Force  bool
+}
+
+func (o *configInitOptions) Flags() (fss cliflag.NamedFlagSets) {
+	fs := fss.FlagSet("init")
+	fs.StringVar(&o.Format, "format", "yaml", "Format of the configuration file, one of yaml, toml and json.")
+	fs.BoolVar(&o.Force, "force", false, "Overwrite the configuration file if it exists.")
+	return fss
+}
+
+func (o *configInitOptions) Validate() []error {
+	if o.Format != "yaml" && o.Format != "toml" && o.Format != "json" {
+		return []error{fmt.Errorf("unsupported config format %q, must be one of yaml, toml and json", o.Format)}
+	}
+	return nil
+}
+
+// configCommand returns the "config" command which manages the configuration
+// of the App.
+func (a *App) configCommand() *Command {
+	view := &configViewOptions{}
+	initOpts := &configInitOptions{}
+	c := NewCommand(configCommandName, "Manage the configuration.",
+		WithCommandDesc(fmt.Sprintf("Manage the configuration of %s.", a.basename)),
+		WithCommandRunFunc(func(cmd *Command, args []string) error {
+			return cmd.Help()
+		}),
+	)
+	c.builtin = true
+	schema := NewCommand("schema", "Print the JSON Schema of the configuration.",
+		WithCommandRunFunc(func(cmd *Command, args []string) error {
+			return writeConfigSchema(cmd.IOStreams().Out, newConfigSchema(a.opts, a.optsFlagSets))
+		}),
+	)
+	// the schema is consumed by the editors and CI rather than the users
+	schema.CobraCommand().Hidden = true
+	c.AddCommands(
+		NewCommand("path", "Print the configuration files used and the search paths.",
+			WithCommandRunFunc(func(cmd *Command, args []string) error {
+				return a.printConfigPath(cmd.IOStreams().Out)
+			}),
+		),
+		NewCommand("view", "Print the merged effective configuration.",
+			WithCommandCliOptions(view),
+			WithCommandRunFunc(func(cmd *Command, args []string) error {
+				return writeConfig(cmd.IOStreams().Out, a.effectiveConfig().AllSettings(), view.Output)
+			}),
+		),
+		NewCommand("get", "Print the effective value of a configuration key.",
+			WithCommandRunFunc(func(cmd *Command, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("accepts 1 arg, received %d", len(args))
+				}
+				return a.printConfigValue(cmd.IOStreams().Out, args[0])
+			}),
+		),
+		NewCommand("set", "Set the value of a configuration key in the configuration file.",
+			WithCommandRunFunc(func(cmd *Command, args []string) error {
+				if len(args) != 2 {
+					return fmt.Errorf("accepts 2 arg(s), received %d", len(args))
+				}
+				return a.editConfigFile(func(editor configEditor) error {
+					return editor.set(strings.Split(args[0], "."), parseConfigValue(args[1]))
+				})
+			}),
+		),
+		NewCommand("unset", "Remove a configuration key from the configuration file.",
+			WithCommandRunFunc(func(cmd *Command, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("accepts 1 arg, received %d", len(args))
+				}
+				return a.editConfigFile(func(editor configEditor) error {
+					found, err := editor.unset(strings.Split(args[0], "."))
+					if err == nil && !found {
+						err = fmt.Errorf("key %q is not found", args[0])
+					}
+					return err
+				})
+			}),
+		),
+		NewCommand("init", "Generate a configuration file with the default values.",
+			WithCommandDesc(fmt.Sprintf("Generate a configuration file with the default values of the flags, "+
+				"the file defaults to %s.<format> in the working directory.", a.basename)),
+			WithCommandCliOptions(initOpts),
+			WithCommandRunFunc(func(cmd *Command, args []string) error {
+				if len(args) > 1 {
+					return fmt.Errorf("accepts at most 1 arg, received %d", len(args))
+				}
+				file := a.basename + "." + initOpts.Format
+				if len(args) == 1 {
+					file = args[0]
+				}
+				if err := a.initConfigFile(file, initOpts.Format, initOpts.Force); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintf(cmd.IOStreams().Out, "Configuration file %s is created.\n", file)
+				return nil
+			}),
+		),
+		NewCommand("validate", "Complete and validate the options without running the application.",
+			WithCommandRunFunc(func(cmd *Command, args []string) error {
+				if err := a.validateConfig(); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintln(cmd.IOStreams().Out, "The configuration is valid.")
+				return nil
+			}),
+		),
+		schema,
+	)
+	return c
+}
+
+func (a *App) printConfigPath(w io.Writer) error {
+	files := a.configFilesUsed()
+	if len(files) == 0 {
+		_, _ = fmt.Fprintln(w, "No configuration file is used.")
+	} else {
+		_, _ = fmt.Fprintln(w, "Configuration files used:")
+		for _, file := range files {
+			abs, _ := filepath.Abs(file)
+			_, _ = fmt.Fprintf(w, "  %s\n", abs)
+		}
+	}
+	_, _ = fmt.Fprintln(w, "Search paths:")
+	for _, path := range a.configSearchPaths(a.basename) {
+		abs, _ := filepath.Abs(path)
+		_, _ = fmt.Fprintf(w, "  %s\n", abs)
+	}
+	return nil
+}
+
+// optionsFlagSet returns the flags of the options.
+func (a *App) optionsFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet(a.basename, pflag.ContinueOnError)
+	for _, name := range a.optsFlagSets.Order {
+		fs.AddFlagSet(a.optsFlagSets.FlagSets[name])
+	}
+	return fs
+}
+
+// effectiveConfig returns the viper instance of the App, which is bound to the
+// flags of the options, so the defaults of the flags are included.
+func (a *App) effectiveConfig() *viper.Viper {
+	_ = a.viper.BindPFlags(a.optionsFlagSet())
+	return a.viper
+}
+
+func (a *App) printConfigValue(w io.Writer, key string) error {
+	v := a.effectiveConfig()
+	if !v.IsSet(key) {
+		return fmt.Errorf("key %q is not set", key)
+	}
+	switch value := v.Get(key).(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, _ = w.Write(data)
+	default:
+		_, _ = fmt.Fprintln(w, value)
+	}
+	return nil
+}
+
+func (a *App) validateConfig() error {
+	if a.opts == nil {
+		return nil
+	}
+	if err := a.loadOptions(a.optionsFlagSet()); err != nil {
+		return err
+	}
+	return completeAndValidate(a.opts)
+}
+
+// writeConfig writes the settings in the given format.
+func writeConfig(w io.Writer, settings map[string]interface{}, format string) error {
+	v := viper.New()
+	if err := v.MergeConfigMap(settings); err != nil {
+		return err
+	}
+	v.SetConfigType(format)
+	return v.WriteConfigTo(w)
+}
+
+// parseConfigValue parses the value as YAML, so "true", "1" and "[a, b]" are
+// typed, it falls back to the string.
+func parseConfigValue(value string) interface{} {
+	var parsed interface{}
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil || parsed == nil {
+		return value
+	}
+	return parsed
+}
+
+// setConfigValue sets the value of the dotted key, e.g. "server.port".
+func setConfigValue(settings map[string]interface{}, key string, value interface{}) {
+	path := strings.Split(strings.ToLower(key), ".")
+	for _, k := range path[:len(path)-1] {
+		next, ok := settings[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			settings[k] = next
+		}
+		settings = next
+	}
+	settings[path[len(path)-1]] = value
+}
+
+// unsetConfigValue removes the dotted key, it reports whether the key is
+// found.
+func unsetConfigValue(settings map[string]interface{}, key string) bool {
+	path := strings.Split(strings.ToLower(key), ".")
+	for _, k := range path[:len(path)-1] {
+		next, ok := settings[k].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		settings = next
+	}
+	if _, ok := settings[path[len(path)-1]]; !ok {
+		return false
+	}
+	delete(settings, path[len(path)-1])
+	return true
+}
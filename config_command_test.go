@@ -0,0 +1,261 @@
+package jcli_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func TestAppConfigCommand(t *testing.T) {
+	setup := func(t *testing.T) string {
+		isolateConfigDirs(t)
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte("username: file-user\nserver:\n  port: 8080\n"), 0o600))
+		abs, _ := filepath.Abs("testApp.yaml")
+		return abs
+	}
+	execute := func(t *testing.T, args ...string) (string, error) {
+		r, w, _ := os.Pipe()
+		tmp := os.Stdout
+		defer func() {
+			os.Stdout = tmp
+		}()
+		os.Stdout = w
+		os.Args = append([]string{"testApp"}, args...)
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(&completableCliOptions{}),
+			jcli.EnablePersistentCliOptions(),
+			jcli.EnableConfigCommand(),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		_, err := app.Execute(context.Background())
+		_ = w.Close()
+		stdout, _ := io.ReadAll(r)
+		return string(stdout), err
+	}
+
+	t.Run("config path", func(t *testing.T) {
+		file := setup(t)
+		stdout, err := execute(t, "config", "path")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "Configuration files used:\n  "+file+"\n")
+		assert.Contains(t, stdout, "Search paths:\n  "+filepath.Dir(file)+"\n")
+	})
+
+	t.Run("config view", func(t *testing.T) {
+		setup(t)
+		stdout, err := execute(t, "config", "view", "-o", "json")
+		assert.NoError(t, err)
+		var settings map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &settings))
+		assert.Equal(t, "file-user", settings["username"])
+		assert.Equal(t, "", settings["password"])
+		assert.Equal(t, map[string]interface{}{"port": float64(8080)}, settings["server"])
+
+		stdout, err = execute(t, "config", "view")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "username: file-user\n")
+
+		_, err = execute(t, "config", "view", "-o", "xml")
+		assert.ErrorContains(t, err, "unsupported output format \"xml\"")
+	})
+
+	t.Run("config get", func(t *testing.T) {
+		setup(t)
+		stdout, err := execute(t, "config", "get", "username")
+		assert.NoError(t, err)
+		assert.Equal(t, "file-user\n", stdout)
+
+		stdout, err = execute(t, "config", "get", "server")
+		assert.NoError(t, err)
+		assert.Equal(t, "port: 8080\n", stdout)
+
+		_, err = execute(t, "config", "get", "missing")
+		assert.ErrorContains(t, err, "key \"missing\" is not set")
+	})
+
+	t.Run("config set and unset", func(t *testing.T) {
+		file := setup(t)
+		_, err := execute(t, "config", "set", "server.port", "9090")
+		assert.NoError(t, err)
+		_, err = execute(t, "config", "set", "server.tls", "true")
+		assert.NoError(t, err)
+		stdout, err := execute(t, "config", "view", "-o", "json")
+		assert.NoError(t, err)
+		var settings map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &settings))
+		assert.Equal(t, map[string]interface{}{"port": float64(9090), "tls": true}, settings["server"])
+
+		_, err = execute(t, "config", "unset", "username")
+		assert.NoError(t, err)
+		content, _ := os.ReadFile(file)
+		assert.NotContains(t, string(content), "username")
+		assert.Contains(t, string(content), "port: 9090")
+
+		_, err = execute(t, "config", "unset", "username")
+		assert.ErrorContains(t, err, "key \"username\" is not found")
+
+		// the persistent options are not required by the config command
+		_, err = execute(t, "config", "set", "username", "new-user")
+		assert.NoError(t, err)
+		stdout, err = execute(t, "config", "get", "username")
+		assert.NoError(t, err)
+		assert.Equal(t, "new-user\n", stdout)
+	})
+
+	t.Run("config set and unset should keep the comments", func(t *testing.T) {
+		setup(t)
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte(`# the user
+userName: file-user # inline
+# the server
+server:
+    port: 8080
+    # the host
+    host: localhost
+`), 0o600))
+		_, err := execute(t, "config", "set", "server.port", "9090")
+		assert.NoError(t, err)
+		_, err = execute(t, "config", "set", "server.tls", "true")
+		assert.NoError(t, err)
+		_, err = execute(t, "config", "unset", "server.host")
+		assert.NoError(t, err)
+		content, _ := os.ReadFile("testApp.yaml")
+		assert.Equal(t, `# the user
+userName: file-user # inline
+# the server
+server:
+    port: 9090
+    tls: true
+`, string(content))
+	})
+
+	t.Run("config set and unset should keep the comments of toml", func(t *testing.T) {
+		setup(t)
+		assert.NoError(t, os.Remove("testApp.yaml"))
+		assert.NoError(t, os.WriteFile("testApp.toml", []byte(`# the user
+userName = "file-user" # inline
+
+# the server
+[server]
+port = 8080
+# the host
+host = "localhost"
+
+[other]
+key = 1
+`), 0o600))
+		_, err := execute(t, "config", "set", "server.port", "9090")
+		assert.NoError(t, err)
+		_, err = execute(t, "config", "set", "server.tls", "true")
+		assert.NoError(t, err)
+		_, err = execute(t, "config", "set", "username", "new-user")
+		assert.NoError(t, err)
+		_, err = execute(t, "config", "unset", "server.host")
+		assert.NoError(t, err)
+		content, _ := os.ReadFile("testApp.toml")
+		assert.Equal(t, `# the user
+userName = 'new-user' # inline
+
+# the server
+[server]
+port = 9090
+tls = true
+
+[other]
+key = 1
+`, string(content))
+	})
+
+	t.Run("config set should keep the order of json", func(t *testing.T) {
+		setup(t)
+		assert.NoError(t, os.Remove("testApp.yaml"))
+		assert.NoError(t, os.WriteFile("testApp.json", []byte(`{"userName": "file-user", "server": {"port": 8080}}`), 0o600))
+		_, err := execute(t, "config", "set", "server.tls", "true")
+		assert.NoError(t, err)
+		content, _ := os.ReadFile("testApp.json")
+		assert.Equal(t, `{
+  "userName": "file-user",
+  "server": {
+    "port": 8080,
+    "tls": true
+  }
+}
+`, string(content))
+	})
+
+	t.Run("config validate", func(t *testing.T) {
+		setup(t)
+		stdout, err := execute(t, "config", "validate")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "The configuration is valid.")
+
+		_, err = execute(t, "config", "unset", "username")
+		assert.NoError(t, err)
+		_, err = execute(t, "config", "validate")
+		assert.ErrorContains(t, err, "username is required")
+	})
+
+	t.Run("config init", func(t *testing.T) {
+		setup(t)
+		assert.NoError(t, os.Remove("testApp.yaml"))
+		stdout, err := execute(t, "config", "init")
+		assert.NoError(t, err)
+		assert.Equal(t, "Configuration file testApp.yaml is created.\n", stdout)
+		content, _ := os.ReadFile("testApp.yaml")
+		assert.Equal(t, "# Fake flags\n\n# fake password.\npassword: \"\"\n# fake username.\nusername: \"\"\n", string(content))
+
+		_, err = execute(t, "config", "init")
+		assert.ErrorContains(t, err, "testApp.yaml already exists, use --force to overwrite it")
+		_, err = execute(t, "config", "init", "--force")
+		assert.NoError(t, err)
+
+		_, err = execute(t, "config", "init", "--format", "toml", "conf/demo.toml")
+		assert.NoError(t, err)
+		content, _ = os.ReadFile(filepath.Join("conf", "demo.toml"))
+		assert.Contains(t, string(content), "# fake username.\nusername = ''\n")
+
+		_, err = execute(t, "config", "init", "--format", "xml")
+		assert.ErrorContains(t, err, "unsupported config format \"xml\"")
+	})
+
+	t.Run("config schema", func(t *testing.T) {
+		setup(t)
+		stdout, err := execute(t, "config", "schema")
+		assert.NoError(t, err)
+		var schema map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &schema))
+		assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", schema["$schema"])
+		assert.Equal(t, map[string]interface{}{
+			"type": "string", "description": "fake username.", "default": "",
+		}, schema["properties"].(map[string]interface{})["username"])
+
+		stdout, err = execute(t, "config", "--help")
+		assert.NoError(t, err)
+		assert.NotContains(t, stdout, "schema")
+	})
+
+	t.Run("config set without config file", func(t *testing.T) {
+		setup(t)
+		assert.NoError(t, os.Remove("testApp.yaml"))
+		_, err := execute(t, "config", "set", "username", "x")
+		assert.ErrorContains(t, err, "no configuration file is found, specify one with --config")
+
+		custom := filepath.Join(t.TempDir(), "custom.yaml")
+		assert.NoError(t, os.WriteFile(custom, []byte("username: custom-user\n"), 0o600))
+		_, err = execute(t, "config", "set", "--config", custom, "username", "x")
+		assert.NoError(t, err)
+		_, err = execute(t, "-c", custom, "config", "set", "server.port", "9090")
+		assert.NoError(t, err)
+		content, _ := os.ReadFile(custom)
+		assert.Equal(t, "username: x\nserver:\n  port: 9090\n", string(content))
+		assert.NoFileExists(t, "testApp.yaml")
+	})
+}
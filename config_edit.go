@@ -0,0 +1,521 @@
+package jcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// errEditUnsupported is returned by a configEditor which cannot edit the key
+// in place, the file is rewritten from its settings instead.
+var errEditUnsupported = errors.New("the key cannot be edited in place")
+
+// configEditor applies a single edit to a configuration file. The keys are
+// matched case-insensitively, as viper does.
+type configEditor interface {
+	set(path []string, value interface{}) error
+	// unset removes the key, it reports whether the key is found.
+	unset(path []string) (bool, error)
+	encode() ([]byte, error)
+}
+
+// newConfigEditor returns the editor of the format. The YAML, JSON and TOML
+// files are edited in place, so the comments, the order and the case of the
+// keys are preserved.
+func newConfigEditor(data []byte, format string) (configEditor, error) {
+	switch format {
+	case "yaml", "yml":
+		return newYAMLEditor(data, false)
+	case "json":
+		return newYAMLEditor(data, true)
+	case "toml":
+		return newTOMLEditor(data)
+	default:
+		return newSettingsEditor(data, format)
+	}
+}
+
+// editConfigFile edits the configuration file of the highest precedence.
+func (a *App) editConfigFile(edit func(editor configEditor) error) error {
+	files := a.configFilesUsed()
+	if len(files) == 0 {
+		if a.configFlag.disabled {
+			return fmt.Errorf("no configuration file is found in the search paths")
+		}
+		return fmt.Errorf("no configuration file is found, specify one with --%s", a.configFlag.name)
+	}
+	file := files[len(files)-1]
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file) // #nosec G304
+	if err != nil {
+		return err
+	}
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(file), "."))
+	editor, err := newConfigEditor(data, format)
+	if err != nil {
+		return err
+	}
+	err = edit(editor)
+	if errors.Is(err, errEditUnsupported) {
+		if editor, err = newSettingsEditor(data, format); err != nil {
+			return err
+		}
+		err = edit(editor)
+	}
+	if err != nil {
+		return err
+	}
+
+	out, err := editor.encode()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, out, info.Mode().Perm())
+}
+
+// settingsEditor edits the settings read by viper, the file is rewritten from
+// the settings, so the comments are dropped.
+type settingsEditor struct {
+	settings map[string]interface{}
+	format   string
+}
+
+func newSettingsEditor(data []byte, format string) (*settingsEditor, error) {
+	v := viper.New()
+	v.SetConfigType(format)
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &settingsEditor{settings: v.AllSettings(), format: format}, nil
+}
+
+func (e *settingsEditor) set(path []string, value interface{}) error {
+	setConfigValue(e.settings, strings.Join(path, "."), value)
+	return nil
+}
+
+func (e *settingsEditor) unset(path []string) (bool, error) {
+	return unsetConfigValue(e.settings, strings.Join(path, ".")), nil
+}
+
+func (e *settingsEditor) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeConfig(&buf, e.settings, e.format); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlEditor edits the YAML node tree of the document. A JSON document is
+// parsed as YAML, and written back in the order of the nodes.
+type yamlEditor struct {
+	doc  yaml.Node
+	json bool
+}
+
+func newYAMLEditor(data []byte, json bool) (*yamlEditor, error) {
+	e := &yamlEditor{json: json}
+	if err := yaml.Unmarshal(data, &e.doc); err != nil {
+		return nil, err
+	}
+	if e.doc.Kind == 0 {
+		// the document is empty
+		e.doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if e.doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the configuration is not a mapping")
+	}
+	return e, nil
+}
+
+func (e *yamlEditor) set(path []string, value interface{}) error {
+	m := e.doc.Content[0]
+	for i, key := range path {
+		j := yamlKeyIndex(m, key)
+		if j < 0 {
+			node := &yaml.Node{}
+			if err := node.Encode(nestedSettings(path[i+1:], value)); err != nil {
+				return err
+			}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+			return nil
+		}
+
+		old := m.Content[j+1]
+		if i < len(path)-1 && old.Kind == yaml.MappingNode {
+			m = old
+			continue
+		}
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		if i == len(path)-1 {
+			if err := node.Encode(value); err != nil {
+				return err
+			}
+		}
+		// the comments of the replaced value are kept
+		node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		m.Content[j+1] = node
+		m = node
+	}
+	return nil
+}
+
+func (e *yamlEditor) unset(path []string) (bool, error) {
+	m := e.doc.Content[0]
+	for i, key := range path {
+		j := yamlKeyIndex(m, key)
+		if j < 0 {
+			return false, nil
+		}
+		if i == len(path)-1 {
+			m.Content = append(m.Content[:j], m.Content[j+2:]...)
+			return true, nil
+		}
+		if m = m.Content[j+1]; m.Kind != yaml.MappingNode {
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *yamlEditor) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if e.json {
+		if err := writeJSONNode(&buf, e.doc.Content[0]); err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+	}
+
+	indent := yamlIndent(e.doc.Content[0])
+	if indent < 2 {
+		indent = 2
+	}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(&e.doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlKeyIndex returns the index of the key in the mapping node, or -1.
+func yamlKeyIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if strings.EqualFold(m.Content[i].Value, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlIndent returns the indentation of the first nested block mapping of the
+// document, or 0 if there is none.
+func yamlIndent(m *yaml.Node) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		key, value := m.Content[i], m.Content[i+1]
+		if value.Kind != yaml.MappingNode || value.Style&yaml.FlowStyle != 0 || len(value.Content) == 0 {
+			continue
+		}
+		if value.Content[0].Line > key.Line {
+			return value.Content[0].Column - key.Column
+		}
+		if indent := yamlIndent(value); indent > 0 {
+			return indent
+		}
+	}
+	return 0
+}
+
+// writeJSONNode writes the node as JSON in the order of the nodes.
+func writeJSONNode(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return writeJSONNode(buf, n.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err = writeJSONNode(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+// nestedSettings returns the value nested by the path.
+func nestedSettings(path []string, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+	return map[string]interface{}{path[0]: nestedSettings(path[1:], value)}
+}
+
+// tomlEditor edits the TOML document as text by the positions of the parsed
+// expressions, so the untouched lines are kept as is.
+type tomlEditor struct {
+	data  []byte
+	exprs []tomlExpr
+}
+
+// tomlExpr is a top level expression of the TOML document.
+type tomlExpr struct {
+	kind unstable.Kind
+	// path is the full path of a key-value or a table.
+	path []string
+	// start is the offset of the expression, and end is the offset after
+	// the expression including its trailing comment.
+	start, end int
+	// valueStart and valueEnd are the range of the value of a key-value.
+	valueStart, valueEnd int
+}
+
+func newTOMLEditor(data []byte) (*tomlEditor, error) {
+	e := &tomlEditor{data: data}
+	p := unstable.Parser{KeepComments: true}
+	p.Reset(data)
+
+	var table []string
+	for p.NextExpression() {
+		n := p.Expression()
+		expr := tomlExpr{kind: n.Kind}
+		switch n.Kind {
+		case unstable.KeyValue, unstable.Table, unstable.ArrayTable:
+			var keys []string
+			var keyEnd int
+			it := n.Key()
+			for it.Next() {
+				key := it.Node()
+				if keys == nil {
+					expr.start = int(key.Raw.Offset)
+				}
+				keys = append(keys, string(key.Data))
+				keyEnd = int(key.Raw.Offset + key.Raw.Length)
+			}
+			if n.Kind != unstable.KeyValue {
+				expr.start = bytes.LastIndexByte(data[:expr.start], '[')
+				table = keys
+				expr.path = keys
+				break
+			}
+			expr.path = append(append([]string{}, table...), keys...)
+			expr.valueStart = keyEnd + bytes.IndexByte(data[keyEnd:], '=') + 1
+			for expr.valueStart < len(data) && (data[expr.valueStart] == ' ' || data[expr.valueStart] == '\t') {
+				expr.valueStart++
+			}
+			if comment := n.Next(); comment != nil {
+				expr.valueEnd = len(bytes.TrimRight(data[:comment.Raw.Offset], " \t"))
+			}
+		default:
+			expr.start = int(n.Raw.Offset)
+		}
+		e.exprs = append(e.exprs, expr)
+	}
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+
+	// only the whitespaces are between the expressions
+	for i := range e.exprs {
+		next := len(data)
+		if i+1 < len(e.exprs) {
+			next = lineStart(data, e.exprs[i+1].start)
+		}
+		e.exprs[i].end = len(bytes.TrimRight(data[:next], " \t\r\n"))
+		if e.exprs[i].kind == unstable.KeyValue && e.exprs[i].valueEnd == 0 {
+			e.exprs[i].valueEnd = e.exprs[i].end
+		}
+	}
+	return e, nil
+}
+
+func (e *tomlEditor) set(path []string, value interface{}) error {
+	raw, err := tomlValue(value)
+	if err != nil {
+		return err
+	}
+	for _, expr := range e.exprs {
+		if expr.kind == unstable.KeyValue && equalKeyPath(expr.path, path) {
+			e.splice(expr.valueStart, expr.valueEnd, raw)
+			return nil
+		}
+	}
+	for _, expr := range e.exprs {
+		switch {
+		case expr.kind == unstable.Comment:
+		case hasKeyPathPrefix(expr.path, path):
+			// the key is a table
+			return errEditUnsupported
+		case expr.kind != unstable.Table && hasKeyPathPrefix(path, expr.path):
+			// the parent of the key is a value, an inline table or an array
+			// of tables
+			return errEditUnsupported
+		}
+	}
+
+	// the key is added to the table of the longest prefix, or the root table
+	section, depth := -1, 0
+	for i, expr := range e.exprs {
+		if expr.kind == unstable.Table && len(expr.path) > depth && hasKeyPathPrefix(path, expr.path) {
+			section, depth = i, len(expr.path)
+		}
+	}
+	line := tomlKey(path[depth:]) + " = " + raw
+	newline := "\n"
+	if bytes.Contains(e.data, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	after := section
+	for i := section + 1; i < len(e.exprs); i++ {
+		kind := e.exprs[i].kind
+		if kind == unstable.Table || kind == unstable.ArrayTable {
+			break
+		}
+		if kind == unstable.KeyValue {
+			after = i
+		}
+	}
+	switch {
+	case after >= 0:
+		expr := e.exprs[after]
+		indent := ""
+		if expr.kind == unstable.KeyValue {
+			indent = string(e.data[lineStart(e.data, expr.start):expr.start])
+		}
+		e.splice(expr.end, expr.end, newline+indent+line)
+	case len(e.exprs) > 0 && e.exprs[0].kind == unstable.Comment && !e.hasTable():
+		// the document only has the comments
+		end := e.exprs[len(e.exprs)-1].end
+		e.splice(end, end, newline+line)
+	default:
+		e.splice(0, 0, line+newline)
+	}
+	return nil
+}
+
+func (e *tomlEditor) unset(path []string) (bool, error) {
+	for i, expr := range e.exprs {
+		if expr.kind != unstable.KeyValue || !equalKeyPath(expr.path, path) {
+			continue
+		}
+		// the comments right above the key are removed along with the key
+		start := lineStart(e.data, expr.start)
+		for j := i - 1; j >= 0 && e.exprs[j].kind == unstable.Comment; j-- {
+			if between := string(e.data[e.exprs[j].end:start]); between != "\n" && between != "\r\n" {
+				break
+			}
+			start = lineStart(e.data, e.exprs[j].start)
+		}
+		end := expr.end
+		if k := bytes.IndexByte(e.data[end:], '\n'); k >= 0 {
+			end += k + 1
+		} else {
+			end = len(e.data)
+		}
+		e.splice(start, end, "")
+		return true, nil
+	}
+	for _, expr := range e.exprs {
+		if expr.kind != unstable.Comment && hasKeyPathPrefix(expr.path, path) {
+			// the key is a table
+			return false, errEditUnsupported
+		}
+	}
+	return false, nil
+}
+
+func (e *tomlEditor) encode() ([]byte, error) {
+	return e.data, nil
+}
+
+// splice replaces the bytes between start and end with s.
+func (e *tomlEditor) splice(start, end int, s string) {
+	data := make([]byte, 0, len(e.data)-(end-start)+len(s))
+	data = append(data, e.data[:start]...)
+	data = append(data, s...)
+	e.data = append(data, e.data[end:]...)
+}
+
+func (e *tomlEditor) hasTable() bool {
+	for _, expr := range e.exprs {
+		if expr.kind == unstable.Table || expr.kind == unstable.ArrayTable {
+			return true
+		}
+	}
+	return false
+}
+
+// lineStart returns the offset of the line which contains the offset.
+func lineStart(data []byte, offset int) int {
+	return bytes.LastIndexByte(data[:offset], '\n') + 1
+}
+
+// equalKeyPath reports whether the key paths are equal case-insensitively.
+func equalKeyPath(a, b []string) bool {
+	return len(a) == len(b) && hasKeyPathPrefix(a, b)
+}
+
+// hasKeyPathPrefix reports whether the key path begins with the prefix
+// case-insensitively.
+func hasKeyPathPrefix(path, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if !strings.EqualFold(path[i], prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
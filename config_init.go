@@ -0,0 +1,261 @@
+package jcli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultConfigKey is a key of the default configuration generated from a flag.
+type defaultConfigKey struct {
+	name  string
+	value interface{}
+	usage string
+}
+
+// defaultConfigGroup holds the keys generated from a named flag set.
+type defaultConfigGroup struct {
+	name string
+	keys []defaultConfigKey
+}
+
+// title returns the section title of the group, e.g. "Generic flags", it is the
+// same as the one in the help.
+func (g defaultConfigGroup) title() string {
+	return strings.ToUpper(g.name[:1]) + g.name[1:] + " flags"
+}
+
+// bareTOMLKey matches the keys which can be written without quotes in TOML.
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// GenerateDefaultConfig writes a configuration file in the given format, one of
+// yaml, toml and json, from the flags of the NamedFlagSets. Each key carries the
+// default value of the flag, the keys are grouped by the flag set names, and
+// the usages of the flags are written as comments if the format supports.
+// The dotted flag names, e.g. "server.port", are written as nested keys.
+func GenerateDefaultConfig(w io.Writer, fss cliflag.NamedFlagSets, format string) error {
+	groups := defaultConfigGroups(fss)
+	switch format {
+	case "yaml", "yml":
+		return writeDefaultYAML(w, groups)
+	case "toml":
+		return writeDefaultTOML(w, groups)
+	case "json":
+		return writeDefaultJSON(w, groups)
+	}
+	return fmt.Errorf("unsupported config format %q, must be one of yaml, toml and json", format)
+}
+
+// initConfigFile writes the default configuration of the App into the file,
+// the existing file is overwritten only if force is true.
+func (a *App) initConfigFile(file, format string, force bool) error {
+	if _, err := os.Stat(file); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", file)
+	}
+	var buf bytes.Buffer
+	if err := GenerateDefaultConfig(&buf, a.optsFlagSets, format); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0o644)
+}
+
+func defaultConfigGroups(fss cliflag.NamedFlagSets) []defaultConfigGroup {
+	var groups []defaultConfigGroup
+	for _, name := range fss.Order {
+		group := defaultConfigGroup{name: name}
+		fss.FlagSets[name].VisitAll(func(f *pflag.Flag) {
+			if f.Hidden || f.Deprecated != "" {
+				return
+			}
+			_, usage := pflag.UnquoteUsage(f)
+			group.keys = append(group.keys, defaultConfigKey{
+				name:  f.Name,
+				value: flagDefaultValue(f),
+				usage: usage,
+			})
+		})
+		if len(group.keys) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// flagDefaultValue returns the typed default value of the flag.
+func flagDefaultValue(f *pflag.Flag) interface{} {
+	typ := f.Value.Type()
+	switch {
+	case typ == "string":
+		return f.DefValue
+	case strings.HasPrefix(typ, "stringTo"):
+		m := make(map[string]interface{})
+		items, _ := readCSV(strings.TrimSuffix(strings.TrimPrefix(f.DefValue, "["), "]"))
+		for _, item := range items {
+			if k, v, ok := strings.Cut(item, "="); ok {
+				m[k] = v
+			}
+		}
+		return m
+	case strings.HasSuffix(typ, "Slice") || strings.HasSuffix(typ, "Array"):
+		list := make([]interface{}, 0)
+		items, _ := readCSV(strings.TrimSuffix(strings.TrimPrefix(f.DefValue, "["), "]"))
+		for _, item := range items {
+			if strings.HasPrefix(typ, "string") {
+				list = append(list, item)
+			} else {
+				list = append(list, parseConfigValue(item))
+			}
+		}
+		return list
+	}
+	return parseConfigValue(f.DefValue)
+}
+
+func readCSV(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return csv.NewReader(strings.NewReader(value)).Read()
+}
+
+func writeDefaultYAML(w io.Writer, groups []defaultConfigGroup) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, group := range groups {
+		for i, key := range group.keys {
+			top, leaf, err := setYAMLNode(root, strings.Split(key.name, "."), key.value)
+			if err != nil {
+				return err
+			}
+			leaf.HeadComment = key.usage
+			if i > 0 {
+				continue
+			}
+			// the title of the group is the head comment of the top level key if
+			// it is created by the group
+			if top != nil && top != leaf {
+				top.HeadComment = group.title()
+			} else {
+				leaf.HeadComment = group.title() + "\n\n" + key.usage
+			}
+		}
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// setYAMLNode sets the value of the key path in the mapping node. It returns
+// the top level key node if it is created, and the node of the last key.
+func setYAMLNode(m *yaml.Node, path []string, value interface{}) (top, leaf *yaml.Node, err error) {
+	for i, k := range path[:len(path)-1] {
+		var next *yaml.Node
+		for j := 0; j < len(m.Content); j += 2 {
+			if m.Content[j].Value == k && m.Content[j+1].Kind == yaml.MappingNode {
+				next = m.Content[j+1]
+				break
+			}
+		}
+		if next == nil {
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: k}
+			if i == 0 {
+				top = keyNode
+			}
+			next = &yaml.Node{Kind: yaml.MappingNode}
+			m.Content = append(m.Content, keyNode, next)
+		}
+		m = next
+	}
+	valueNode := &yaml.Node{}
+	if err = valueNode.Encode(value); err != nil {
+		return nil, nil, err
+	}
+	leaf = &yaml.Node{Kind: yaml.ScalarNode, Value: path[len(path)-1]}
+	if len(path) == 1 {
+		top = leaf
+	}
+	m.Content = append(m.Content, leaf, valueNode)
+	return top, leaf, nil
+}
+
+func writeDefaultTOML(w io.Writer, groups []defaultConfigGroup) error {
+	for i, group := range groups {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintf(w, "# %s\n", group.title())
+		for _, key := range group.keys {
+			value, err := tomlValue(key.value)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(w, "\n%s%s = %s\n", tomlComment(key.usage), tomlKey(strings.Split(key.name, ".")), value)
+		}
+	}
+	return nil
+}
+
+// tomlValue returns the TOML representation of the value.
+func tomlValue(value interface{}) (string, error) {
+	// the value is encoded as a key of a table, then the key is trimmed
+	data, err := toml.Marshal(map[string]interface{}{"v": value})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(string(data), "v = ")), nil
+}
+
+// tomlKey returns the dotted TOML key of the path, the keys which are not bare
+// are quoted.
+func tomlKey(path []string) string {
+	keys := make([]string, len(path))
+	for i, k := range path {
+		keys[i] = k
+		if !bareTOMLKey.MatchString(k) {
+			keys[i] = strconv.Quote(k)
+		}
+	}
+	return strings.Join(keys, ".")
+}
+
+func tomlComment(usage string) string {
+	if usage == "" {
+		return ""
+	}
+	return "# " + strings.ReplaceAll(usage, "\n", "\n# ") + "\n"
+}
+
+func writeDefaultJSON(w io.Writer, groups []defaultConfigGroup) error {
+	settings := make(map[string]interface{})
+	for _, group := range groups {
+		for _, key := range group.keys {
+			setConfigValue(settings, key.name, key.value)
+		}
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,77 @@
+package jcli_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func TestGenerateDefaultConfig(t *testing.T) {
+	var fss cliflag.NamedFlagSets
+	fs := fss.FlagSet("generic")
+	fs.String("username", "root", "The `NAME` of the user.")
+	fs.StringSlice("tags", []string{"a", "b"}, "Tags of the user.")
+	fs.String("hidden", "", "Hidden flag.")
+	_ = fs.MarkHidden("hidden")
+	fs = fss.FlagSet("server")
+	fs.Int("server.port", 8080, "Port of the server.")
+	fs.Duration("server.timeout", time.Second, "Timeout of the server.")
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, jcli.GenerateDefaultConfig(&buf, fss, "yaml"))
+		assert.Equal(t, `# Generic flags
+
+# Tags of the user.
+tags:
+  - a
+  - b
+# The NAME of the user.
+username: root
+# Server flags
+server:
+  # Port of the server.
+  port: 8080
+  # Timeout of the server.
+  timeout: 1s
+`, buf.String())
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, jcli.GenerateDefaultConfig(&buf, fss, "toml"))
+		assert.Equal(t, `# Generic flags
+
+# Tags of the user.
+tags = ['a', 'b']
+
+# The NAME of the user.
+username = 'root'
+
+# Server flags
+
+# Port of the server.
+server.port = 8080
+
+# Timeout of the server.
+server.timeout = '1s'
+`, buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, jcli.GenerateDefaultConfig(&buf, fss, "json"))
+		assert.JSONEq(t, `{"tags": ["a", "b"], "username": "root", "server": {"port": 8080, "timeout": "1s"}}`, buf.String())
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := jcli.GenerateDefaultConfig(&buf, fss, "xml")
+		assert.ErrorContains(t, err, "unsupported config format \"xml\"")
+	})
+}
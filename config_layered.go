@@ -0,0 +1,84 @@
+package jcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// readLayeredConfig merges the configuration files found in the search paths
+// and the file specified by the config flag, the later ones take precedence.
+// The maps are merged recursively, the other values including the lists are
+// replaced.
+func (a *App) readLayeredConfig(basename string) error {
+	var files []string
+	paths := a.configSearchPaths(basename)
+	for i := len(paths) - 1; i >= 0; i-- {
+		if file := findConfigFile(paths[i], basename); file != "" {
+			files = append(files, file)
+		}
+	}
+	if a.configFlag.filename != "" {
+		files = append(files, a.configFlag.filename)
+	}
+
+	merged := make(map[string]interface{})
+	for _, file := range files {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("read %s: %w", file, err)
+		}
+		mergeConfig(merged, v.AllSettings())
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	a.viper.SetConfigType("json")
+	if err = a.viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return err
+	}
+	a.configFiles = files
+	if len(files) > 0 {
+		a.logger.Debugf("%s Config files merged (from the lowest precedence): %s",
+			progressMessage, strings.Join(files, ", "))
+	}
+	return nil
+}
+
+// findConfigFile returns the configuration file named basename with one of
+// the supported extensions in the given directory.
+func findConfigFile(dir, basename string) string {
+	for _, ext := range viper.SupportedExts {
+		file := filepath.Join(dir, basename+"."+ext)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file
+		}
+	}
+	return ""
+}
+
+// mergeConfig deep merges src into dst. The nested maps are merged
+// recursively, the other values in src replace the ones in dst.
+func mergeConfig(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, ok := value.(map[string]interface{})
+		if !ok {
+			dst[key] = value
+			continue
+		}
+		dstMap, ok := dst[key].(map[string]interface{})
+		if !ok {
+			dstMap = make(map[string]interface{})
+			dst[key] = dstMap
+		}
+		mergeConfig(dstMap, srcMap)
+	}
+}
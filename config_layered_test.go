@@ -0,0 +1,107 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+type layeredCliOptions struct {
+	Username string
+	Server   struct {
+		Host string
+		Port int
+	}
+	Tags []string
+}
+
+func (o *layeredCliOptions) Flags() (fss cliflag.NamedFlagSets) {
+	fs := fss.FlagSet("layered")
+	fs.StringVar(&o.Username, "username", o.Username, "layered username.")
+	return fss
+}
+
+func (o *layeredCliOptions) Validate() []error {
+	return nil
+}
+
+func TestAppLayeredConfig(t *testing.T) {
+	home, _, _ := isolateConfigDirs(t)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(home, ".testApp"), 0o750))
+	user := filepath.Join(home, ".testApp", "testApp.yaml")
+	assert.NoError(t, os.WriteFile(user, []byte(`
+username: user
+server:
+  host: user.example.com
+  port: 8080
+tags: [a, b]
+`), 0o600))
+	assert.NoError(t, os.WriteFile("testApp.yaml", []byte(`
+server:
+  port: 9090
+tags: [c]
+`), 0o600))
+	assert.NoError(t, os.WriteFile("explicit.json", []byte(`{"server": {"host": "explicit.example.com"}}`), 0o600))
+
+	t.Run("should merge the layers", func(t *testing.T) {
+		os.Args = []string{"testApp", "-c", "explicit.json"}
+		var buf bytes.Buffer
+		opts := &layeredCliOptions{}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(opts),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableLayeredConfig(),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "user", opts.Username)
+		assert.Equal(t, "explicit.example.com", opts.Server.Host)
+		assert.Equal(t, 9090, opts.Server.Port)
+		assert.Equal(t, []string{"c"}, opts.Tags)
+		assert.Contains(t, buf.String(), "[debug] ==> Config files merged (from the lowest precedence): "+
+			user+", testApp.yaml, explicit.json")
+	})
+
+	t.Run("env and flags should take precedence", func(t *testing.T) {
+		t.Setenv("TESTAPP_SERVER_HOST", "env.example.com")
+		os.Args = []string{"testApp", "--username", "flag"}
+		opts := &layeredCliOptions{}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(opts),
+			jcli.EnableLayeredConfig(),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "flag", opts.Username)
+		assert.Equal(t, "env.example.com", app.Viper().GetString("server.host"))
+		assert.Equal(t, 9090, opts.Server.Port)
+	})
+
+	t.Run("should fail on the invalid layer", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile("invalid.yaml", []byte("server: [\n"), 0o600))
+		os.Args = []string{"testApp", "-c", "invalid.yaml"}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(&layeredCliOptions{}),
+			jcli.EnableLayeredConfig(),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		_, err := app.Execute(context.Background())
+		assert.ErrorContains(t, err, "read invalid.yaml")
+	})
+}
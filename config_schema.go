@@ -0,0 +1,301 @@
+package jcli
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// configSchemaDialect is the JSON Schema dialect of the generated schema.
+	configSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+	// schemaTagName is the struct tag to describe the schema of a field, e.g.
+	// `jsonschema:"required,enum=json|text"`.
+	schemaTagName = "jsonschema"
+)
+
+// configSchema is a JSON Schema of the configuration.
+type configSchema struct {
+	Schema               string                   `json:"$schema,omitempty"`
+	Type                 string                   `json:"type,omitempty"`
+	Description          string                   `json:"description,omitempty"`
+	Default              interface{}              `json:"default,omitempty"`
+	Enum                 []interface{}            `json:"enum,omitempty"`
+	Items                *configSchema            `json:"items,omitempty"`
+	Properties           map[string]*configSchema `json:"properties,omitempty"`
+	AdditionalProperties *configSchema            `json:"additionalProperties,omitempty"`
+	Required             []string                 `json:"required,omitempty"`
+}
+
+// GenerateConfigSchema writes the JSON Schema of the configuration file of the
+// CliOptions. The properties are resolved from the struct which the
+// configuration is unmarshalled into, the flags of the CliOptions add the
+// defaults and the descriptions from the usages. The flags are created from a
+// copy of the options, so the values of the options are kept.
+//
+// A field is required if it is tagged with `jsonschema:"required"` or the flag
+// is marked as required, and its values are restricted by the tag
+// `jsonschema:"enum=a|b"`.
+func GenerateConfigSchema(w io.Writer, opts CliOptions) error {
+	var fss cliflag.NamedFlagSets
+	if opts != nil {
+		fss = copiedOptionsFlags(opts)
+	}
+	return writeConfigSchema(w, newConfigSchema(opts, fss))
+}
+
+// copiedOptionsFlags returns the flags of a copy of the options, since Flags
+// resets the values of the options.
+func copiedOptionsFlags(opts CliOptions) cliflag.NamedFlagSets {
+	if cp, ok := copyOptions(opts); ok {
+		return cp.Flags()
+	}
+	return opts.Flags()
+}
+
+// writeConfigSchema writes the schema as indented JSON.
+func writeConfigSchema(w io.Writer, schema *configSchema) error {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// newConfigSchema returns the schema of the type of the options and the flags
+// of the options.
+func newConfigSchema(opts CliOptions, fss cliflag.NamedFlagSets) *configSchema {
+	schema := &configSchema{Type: "object"}
+	if opts != nil {
+		schema = structSchema(reflect.TypeOf(opts))
+	}
+	for _, name := range fss.Order {
+		fss.FlagSets[name].VisitAll(func(f *pflag.Flag) {
+			addFlagSchema(schema, f)
+		})
+	}
+	schema.Schema = configSchemaDialect
+	return schema
+}
+
+// typeSchema returns the schema of the Go type, the keys of the structs are
+// resolved as mapstructure does.
+func typeSchema(t reflect.Type) *configSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return &configSchema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &configSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &configSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &configSchema{Type: "number"}
+	case reflect.String:
+		return &configSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &configSchema{Type: "array", Items: typeSchema(t.Elem())}
+	case reflect.Map:
+		return &configSchema{Type: "object", AdditionalProperties: typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	return &configSchema{}
+}
+
+func structSchema(t reflect.Type) *configSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	schema := &configSchema{Type: "object"}
+	if t.Kind() != reflect.Struct {
+		return schema
+	}
+	schema.Properties = make(map[string]*configSchema)
+	addStructFields(schema, t)
+	return schema
+}
+
+func addStructFields(schema *configSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, squash := mapstructureKey(field)
+		if name == "-" {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if squash && ft.Kind() == reflect.Struct {
+			addStructFields(schema, ft)
+			continue
+		}
+
+		prop := typeSchema(field.Type)
+		required, enum := parseSchemaTag(field.Tag.Get(schemaTagName))
+		if len(enum) > 0 {
+			target := prop
+			if prop.Type == "array" && prop.Items != nil {
+				target = prop.Items
+			}
+			for _, v := range enum {
+				target.Enum = append(target.Enum, enumValue(target.Type, v))
+			}
+		}
+		schema.Properties[name] = prop
+		if required {
+			schema.addRequired(name)
+		}
+	}
+}
+
+// mapstructureKey returns the key of the field and whether it is squashed.
+func mapstructureKey(field reflect.StructField) (name string, squash bool) {
+	tag := field.Tag.Get("mapstructure")
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "squash" {
+			squash = true
+		}
+	}
+	if parts[0] != "" {
+		return parts[0], squash
+	}
+	return strings.ToLower(field.Name), squash
+}
+
+func parseSchemaTag(tag string) (required bool, enum []string) {
+	if tag == "" {
+		return false, nil
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		switch {
+		case opt == "required":
+			required = true
+		case strings.HasPrefix(opt, "enum="):
+			enum = strings.Split(strings.TrimPrefix(opt, "enum="), "|")
+		}
+	}
+	return required, enum
+}
+
+// enumValue converts the value of the enum tag to the type of the schema.
+func enumValue(typ, value string) interface{} {
+	switch typ {
+	case "integer":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+// addFlagSchema adds the description, the default value and the required
+// annotation of the flag to the property of the flag, the dotted flag names,
+// e.g. "server.port", are nested properties.
+func addFlagSchema(schema *configSchema, f *pflag.Flag) {
+	path := strings.Split(f.Name, ".")
+	for _, k := range path[:len(path)-1] {
+		next := schema.property(k)
+		if next == nil {
+			next = &configSchema{Type: "object"}
+			schema.setProperty(k, next)
+		}
+		schema = next
+	}
+	key := path[len(path)-1]
+	prop := schema.property(key)
+	if prop == nil {
+		prop = flagTypeSchema(f)
+		schema.setProperty(key, prop)
+	}
+	_, prop.Description = pflag.UnquoteUsage(f)
+	if f.Deprecated != "" {
+		prop.Description += " Deprecated: " + f.Deprecated
+	}
+	prop.Default = flagDefaultValue(f)
+	if required, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok && len(required) > 0 && required[0] == "true" {
+		schema.addRequired(key)
+	}
+}
+
+// flagTypeSchema returns the schema of the flag by its value type, it is used
+// if the flag is not bound to a field.
+func flagTypeSchema(f *pflag.Flag) *configSchema {
+	typ := f.Value.Type()
+	switch {
+	case strings.HasPrefix(typ, "stringTo"):
+		return &configSchema{Type: "object"}
+	case strings.HasSuffix(typ, "Slice") || strings.HasSuffix(typ, "Array"):
+		elem := strings.TrimSuffix(strings.TrimSuffix(typ, "Slice"), "Array")
+		return &configSchema{Type: "array", Items: &configSchema{Type: flagValueType(elem)}}
+	}
+	return &configSchema{Type: flagValueType(typ)}
+}
+
+func flagValueType(typ string) string {
+	switch {
+	case typ == "bool":
+		return "boolean"
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint") || typ == "count":
+		return "integer"
+	case strings.HasPrefix(typ, "float"):
+		return "number"
+	}
+	return "string"
+}
+
+// property returns the property of the key, keys are case-insensitive as the
+// configuration is.
+func (s *configSchema) property(key string) *configSchema {
+	for name, prop := range s.Properties {
+		if strings.EqualFold(name, key) {
+			return prop
+		}
+	}
+	return nil
+}
+
+func (s *configSchema) setProperty(key string, prop *configSchema) {
+	if s.Properties == nil {
+		s.Properties = make(map[string]*configSchema)
+	}
+	s.Type = "object"
+	s.Properties[key] = prop
+}
+
+func (s *configSchema) addRequired(key string) {
+	for _, name := range s.Required {
+		if strings.EqualFold(name, key) {
+			return
+		}
+	}
+	s.Required = append(s.Required, key)
+	sort.Strings(s.Required)
+}
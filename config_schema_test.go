@@ -0,0 +1,73 @@
+package jcli_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+type schemaServerOptions struct {
+	Port    int `jsonschema:"enum=80|443"`
+	Timeout time.Duration
+}
+
+type schemaCliOptions struct {
+	fakeCliOptions `mapstructure:",squash"`
+	Format         string `jsonschema:"required,enum=json|text"`
+	Server         schemaServerOptions
+	Tags           []string          `mapstructure:"tags"`
+	Labels         map[string]string `mapstructure:"labels"`
+	Ignored        string            `mapstructure:"-"`
+	internal       string
+}
+
+func (o *schemaCliOptions) Flags() (fss cliflag.NamedFlagSets) {
+	fss = o.fakeCliOptions.Flags()
+	fs := fss.FlagSet("schema")
+	fs.StringVar(&o.Format, "format", "json", "Log `FORMAT`.")
+	fs.IntVar(&o.Server.Port, "server.port", 443, "Port of the server.")
+	fs.DurationVar(&o.Server.Timeout, "server.timeout", time.Second, "Timeout of the server.")
+	fs.Bool("debug", false, "Enable debug.")
+	fs.String("token", "", "Token of the server.")
+	_ = fs.SetAnnotation("token", cobra.BashCompOneRequiredFlag, []string{"true"})
+	return fss
+}
+
+func (o *schemaCliOptions) Validate() []error {
+	return nil
+}
+
+func TestGenerateConfigSchema(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &schemaCliOptions{Format: "text"}
+	assert.NoError(t, jcli.GenerateConfigSchema(&buf, opts))
+	assert.JSONEq(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "username": {"type": "string", "description": "fake username.", "default": ""},
+    "password": {"type": "string", "description": "fake password.", "default": ""},
+    "format": {"type": "string", "description": "Log FORMAT.", "default": "json", "enum": ["json", "text"]},
+    "server": {
+      "type": "object",
+      "properties": {
+        "port": {"type": "integer", "description": "Port of the server.", "default": 443, "enum": [80, 443]},
+        "timeout": {"type": "string", "description": "Timeout of the server.", "default": "1s"}
+      }
+    },
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+    "debug": {"type": "boolean", "description": "Enable debug.", "default": false},
+    "token": {"type": "string", "description": "Token of the server.", "default": ""}
+  },
+  "required": ["format", "token"]
+}`, buf.String())
+	// the values of the options are kept
+	assert.Equal(t, "text", opts.Format)
+}
@@ -0,0 +1,137 @@
+package jcli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const showConfigSourcesFlagName = "show-config-sources"
+
+// ConfigSourceKind is the kind of the source of a configuration value.
+type ConfigSourceKind string
+
+const (
+	// ConfigSourceFlag means the value is set by a command line flag.
+	ConfigSourceFlag ConfigSourceKind = "flag"
+	// ConfigSourceEnv means the value is set by an environment variable.
+	ConfigSourceEnv ConfigSourceKind = "env"
+	// ConfigSourceFile means the value is set by a configuration file.
+	ConfigSourceFile ConfigSourceKind = "file"
+	// ConfigSourceDefault means the value is the default value of the flag.
+	ConfigSourceDefault ConfigSourceKind = "default"
+)
+
+// ConfigSource describes where the effective value of a configuration key
+// comes from.
+type ConfigSource struct {
+	// Key is the configuration key, e.g. "server.port".
+	Key string
+	// Value is the effective value, the sensitive values are redacted.
+	Value string
+	// Source is the kind of the source.
+	Source ConfigSourceKind
+	// Location is the flag, the environment variable or the file and line
+	// of the value, e.g. "--server.port", "$DEMO_SERVER_PORT" and
+	// "demo.yaml:3". It is empty for the default values.
+	Location string
+}
+
+// ConfigSources returns the sources of the configuration keys of the App, the
+// keys are the flags of the CliOptions and the keys in the configuration
+// files. The sources are resolved in the same precedence as Viper: the flags,
+// the environment variables, the configuration files and the defaults.
+// It should be called after the configuration is read, e.g. in the run
+// function.
+func (a *App) ConfigSources() []ConfigSource {
+	flags := a.optionsFlagSet()
+	v := a.effectiveConfig()
+
+	keys := make(map[string]bool)
+	flags.VisitAll(func(f *pflag.Flag) {
+		keys[strings.ToLower(f.Name)] = true
+	})
+
+	// the files from the highest precedence
+	var files []configFileSettings
+	used := a.configFilesUsed()
+	for i := len(used) - 1; i >= 0; i-- {
+		fv := viper.New()
+		fv.SetConfigFile(used[i])
+		if err := fv.ReadInConfig(); err != nil {
+			continue
+		}
+		for _, key := range fv.AllKeys() {
+			keys[key] = true
+		}
+		files = append(files, configFileSettings{file: used[i], viper: fv, lines: configKeyLines(used[i])})
+	}
+
+	sources := make([]ConfigSource, 0, len(keys))
+	for key := range keys {
+		source := ConfigSource{Key: key, Value: fmt.Sprint(v.Get(key))}
+		if source.Value != "" && isSensitiveKey(key) {
+			source.Value = redactedValue
+		}
+		source.Source, source.Location = a.configKeySource(key, flags, files)
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Key < sources[j].Key
+	})
+	return sources
+}
+
+// configFileSettings holds the settings read from a configuration file.
+type configFileSettings struct {
+	file  string
+	viper *viper.Viper
+	lines map[string]int
+}
+
+func (a *App) configKeySource(key string, flags *pflag.FlagSet, files []configFileSettings) (ConfigSourceKind, string) {
+	var flag *pflag.Flag
+	flags.VisitAll(func(f *pflag.Flag) {
+		if strings.EqualFold(f.Name, key) {
+			flag = f
+		}
+	})
+	if flag != nil && flag.Changed {
+		return ConfigSourceFlag, "--" + flag.Name
+	}
+	envs := []string{envPrefix(a.basename) + "_" + strings.ToUpper(envKeyReplacer.Replace(key))}
+	if flag != nil {
+		envs = flagEnvNames(envPrefix(a.basename), flag)
+	}
+	for _, env := range envs {
+		if _, ok := os.LookupEnv(env); ok {
+			return ConfigSourceEnv, "$" + env
+		}
+	}
+	for _, settings := range files {
+		if !settings.viper.IsSet(key) {
+			continue
+		}
+		if line := settings.lines[key]; line > 0 {
+			return ConfigSourceFile, fmt.Sprintf("%s:%d", settings.file, line)
+		}
+		return ConfigSourceFile, settings.file
+	}
+	return ConfigSourceDefault, ""
+}
+
+// printConfigSources prints the sources of the configuration keys as a table.
+func (a *App) printConfigSources(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE\tLOCATION")
+	for _, source := range a.ConfigSources() {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", source.Key, source.Value, source.Source, source.Location)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,80 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func TestAppConfigSources(t *testing.T) {
+	setup := func(t *testing.T) string {
+		isolateConfigDirs(t)
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte("username: file-user\nserver:\n  port: 8080\n"), 0o600))
+		file, _ := filepath.Abs("testApp.yaml")
+		return file
+	}
+
+	t.Run("ConfigSources should resolve the source of each key", func(t *testing.T) {
+		file := setup(t)
+		t.Setenv("TESTAPP_PASSWORD", "secret")
+		os.Args = []string{"testApp", "--username", "flag-user"}
+		var sources []jcli.ConfigSource
+		var app *jcli.App
+		app = jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(&fakeCliOptions{}),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+			jcli.WithRunFunc(func() error {
+				sources = app.ConfigSources()
+				return nil
+			}),
+		)
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []jcli.ConfigSource{
+			{Key: "password", Value: "******", Source: jcli.ConfigSourceEnv, Location: "$TESTAPP_PASSWORD"},
+			{Key: "server.port", Value: "8080", Source: jcli.ConfigSourceFile, Location: file + ":3"},
+			{Key: "username", Value: "flag-user", Source: jcli.ConfigSourceFlag, Location: "--username"},
+		}, sources)
+	})
+
+	t.Run("--show-config-sources should print the sources and quit", func(t *testing.T) {
+		file := setup(t)
+		os.Args = []string{"testApp", "--show-config-sources"}
+		r, w, _ := os.Pipe()
+		tmp := os.Stdout
+		defer func() {
+			os.Stdout = tmp
+		}()
+		os.Stdout = w
+		var buf bytes.Buffer
+		log := newTestLogger(&buf)
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(&fakeCliOptions{}),
+			jcli.WithLogger(log),
+			jcli.DisableVersion(),
+			jcli.WithRunFunc(func() error {
+				log.Infof("application running")
+				return nil
+			}),
+		)
+		_, err := app.Execute(context.Background())
+		_ = w.Close()
+		stdout, _ := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.NotContains(t, buf.String(), "application running")
+		assert.Equal(t, "KEY          VALUE      SOURCE   LOCATION\n"+
+			"password                default  \n"+
+			"server.port  8080       file     "+file+":3\n"+
+			"username     file-user  file     "+file+":1\n", string(stdout))
+	})
+}
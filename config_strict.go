@@ -0,0 +1,175 @@
+package jcli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shipengqi/errors"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// unknownConfigKeyError reports a key in the configuration file which is not
+// unmarshalled into the options.
+type unknownConfigKeyError struct {
+	file       string
+	line       int
+	key        string
+	suggestion string
+}
+
+func (e *unknownConfigKeyError) Error() string {
+	location := e.file
+	if e.line > 0 {
+		location = fmt.Sprintf("%s:%d", e.file, e.line)
+	}
+	msg := fmt.Sprintf("%s: unknown configuration key %q", location, e.key)
+	if e.suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.suggestion)
+	}
+	return msg
+}
+
+// checkConfigKeys returns an aggregated error of the keys in the configuration
+// files used which are unknown to the options. The sections of the sub
+// commands are skipped, they are unmarshalled into the options of the sub
+// commands.
+func (a *App) checkConfigKeys() error {
+	if !a.strictConfig || a.opts == nil {
+		return nil
+	}
+	schema := newConfigSchema(a.opts, a.optsFlagSets)
+	known := schema.keys("")
+	skipped := make(map[string]bool)
+	for _, sub := range a.cmd.Commands() {
+		skipped[strings.ToLower(sub.Name())] = true
+	}
+
+	var errs []error
+	for _, file := range a.configFilesUsed() {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("read %s: %w", file, err)
+		}
+		var unknown []string
+		for key, value := range v.AllSettings() {
+			if skipped[key] {
+				continue
+			}
+			unknown = append(unknown, unknownConfigKeys(schema, key, value)...)
+		}
+		sort.Strings(unknown)
+		lines := configKeyLines(file)
+		for _, key := range unknown {
+			errs = append(errs, &unknownConfigKeyError{
+				file:       file,
+				line:       lines[key],
+				key:        key,
+				suggestion: suggestConfigKey(key, known),
+			})
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+// unknownConfigKeys returns the dotted keys under the given key which are not
+// described by the schema. The keys of the maps are not checked.
+func unknownConfigKeys(schema *configSchema, key string, value interface{}) []string {
+	prop := schema.property(key[strings.LastIndex(key, ".")+1:])
+	if prop == nil {
+		return []string{key}
+	}
+	settings, ok := value.(map[string]interface{})
+	if !ok || prop.Properties == nil {
+		return nil
+	}
+	var unknown []string
+	for k, v := range settings {
+		unknown = append(unknown, unknownConfigKeys(prop, key+"."+k, v)...)
+	}
+	return unknown
+}
+
+// keys returns the dotted keys of the properties of the schema.
+func (s *configSchema) keys(prefix string) []string {
+	var keys []string
+	for name, prop := range s.Properties {
+		key := strings.ToLower(prefix + name)
+		keys = append(keys, key)
+		keys = append(keys, prop.keys(key+".")...)
+	}
+	return keys
+}
+
+// configKeyLines returns the lines of the dotted keys in the configuration
+// file, it is supported by the YAML and JSON files only.
+func configKeyLines(file string) map[string]int {
+	lines := make(map[string]int)
+	switch strings.TrimPrefix(filepath.Ext(file), ".") {
+	case "yaml", "yml", "json":
+	default:
+		return lines
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return lines
+	}
+	// JSON is a subset of YAML, so the YAML parser reports the lines of both
+	var doc yaml.Node
+	if err = yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return lines
+	}
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := strings.ToLower(prefix + node.Content[i].Value)
+			lines[key] = node.Content[i].Line
+			walk(node.Content[i+1], key+".")
+		}
+	}
+	walk(doc.Content[0], "")
+	return lines
+}
+
+// suggestConfigKey returns the known key which is closest to the unknown key,
+// or an empty string if none of them is close enough.
+func suggestConfigKey(key string, known []string) string {
+	candidates := append([]string(nil), known...)
+	sort.Strings(candidates)
+	suggestion := ""
+	best := max(2, len(key)/3) + 1
+	for _, candidate := range candidates {
+		if d := editDistance(key, candidate); d < best {
+			suggestion, best = candidate, d
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
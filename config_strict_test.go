@@ -0,0 +1,95 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func TestAppStrictConfig(t *testing.T) {
+	execute := func(t *testing.T, content, file string, opts ...jcli.Option) error {
+		isolateConfigDirs(t)
+		assert.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+		// the token flag of schemaCliOptions is required
+		os.Args = []string{"testApp", "--token", "x"}
+		var buf bytes.Buffer
+		app := jcli.New("simple", append([]jcli.Option{
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(&schemaCliOptions{}),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+			jcli.WithRunFunc(func() error { return nil }),
+		}, opts...)...)
+		app.AddCommands(jcli.NewCommand("sub1", "sub1 command"))
+		_, err := app.Execute(context.Background())
+		return err
+	}
+
+	yamlContent := `usrname: root
+server:
+  prot: 80
+  timeout: 1s
+zzz: true
+labels:
+  anything: x
+sub1:
+  anything: x
+`
+
+	t.Run("unknown keys are ignored by default", func(t *testing.T) {
+		err := execute(t, yamlContent, "testApp.yaml")
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown keys in yaml", func(t *testing.T) {
+		err := execute(t, yamlContent, "testApp.yaml", jcli.EnableStrictConfig())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `testApp.yaml:1: unknown configuration key "usrname", did you mean "username"?`)
+		assert.Contains(t, err.Error(), `testApp.yaml:3: unknown configuration key "server.prot", did you mean "server.port"?`)
+		assert.Contains(t, err.Error(), `testApp.yaml:5: unknown configuration key "zzz"`)
+		assert.NotContains(t, err.Error(), "labels")
+		assert.NotContains(t, err.Error(), "sub1")
+	})
+
+	t.Run("unknown keys in json", func(t *testing.T) {
+		err := execute(t, "{\n  \"username\": \"root\",\n  \"pasword\": \"x\"\n}\n", "testApp.json", jcli.EnableStrictConfig())
+		assert.ErrorContains(t, err, `testApp.json:3: unknown configuration key "pasword", did you mean "password"?`)
+	})
+
+	t.Run("unknown keys in toml", func(t *testing.T) {
+		err := execute(t, "username = 'root'\nformt = 'json'\n", "testApp.toml", jcli.EnableStrictConfig())
+		assert.ErrorContains(t, err, `testApp.toml: unknown configuration key "formt", did you mean "format"?`)
+	})
+
+	t.Run("known keys", func(t *testing.T) {
+		err := execute(t, "username: root\nserver:\n  port: 80\nlabels:\n  a: b\n", "testApp.yaml", jcli.EnableStrictConfig())
+		assert.NoError(t, err)
+	})
+
+	t.Run("should keep the parsed flags", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte("format: json\nserver:\n  timeout: 3s\n"), 0o600))
+		os.Args = []string{"testApp", "--token", "x", "--format", "text", "--server.port", "80"}
+		opts := &schemaCliOptions{}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(opts),
+			jcli.WithLogger(newTestLogger(&bytes.Buffer{})),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+			jcli.EnableStrictConfig(),
+		)
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "text", opts.Format)
+		assert.Equal(t, 80, opts.Server.Port)
+		assert.Equal(t, 3*time.Second, opts.Server.Timeout)
+	})
+}
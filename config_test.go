@@ -0,0 +1,379 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+// isolateConfigDirs isolates the test from the configuration files of the user
+// and the system, the working directory is changed to a temporary one.
+func isolateConfigDirs(t *testing.T) (home, configHome, configDir string) {
+	home, configHome, configDir = t.TempDir(), t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_CONFIG_DIRS", configDir)
+	t.Chdir(t.TempDir())
+	return home, configHome, configDir
+}
+
+func TestCommandConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	err := os.WriteFile("testApp.yaml", []byte(`
+username: root
+sub1:
+  username: file-user
+  password: file-pass
+  sub1-sub1:
+    username: nested-user
+`), 0o600)
+	assert.NoError(t, err)
+
+	newApp := func(sub1, sub2 *fakeCliOptions) *jcli.App {
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		cmd := jcli.NewCommand("sub1", "sub1 command description",
+			jcli.WithCommandCliOptions(sub1),
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				return nil
+			}),
+		)
+		cmd.AddCommands(jcli.NewCommand("sub1-sub1", "sub1-sub1 command description",
+			jcli.WithCommandCliOptions(sub2),
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				return nil
+			}),
+		))
+		app.AddCommands(cmd)
+		return app
+	}
+
+	t.Run("should load the options from the config section", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub1"}
+		opts := &fakeCliOptions{}
+		_, err := newApp(opts, &fakeCliOptions{}).Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "file-user", opts.Username)
+		assert.Equal(t, "file-pass", opts.Password)
+	})
+
+	t.Run("should load the options of the nested section", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub1", "sub1-sub1"}
+		opts := &fakeCliOptions{}
+		_, err := newApp(&fakeCliOptions{}, opts).Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "nested-user", opts.Username)
+		assert.Empty(t, opts.Password)
+	})
+
+	t.Run("env should override the config and flags should override the env", func(t *testing.T) {
+		t.Setenv("TESTAPP_SUB1_USERNAME", "env-user")
+		t.Setenv("TESTAPP_SUB1_PASSWORD", "env-pass")
+		os.Args = []string{"testApp", "sub1", "--username", "flag-user"}
+		opts := &fakeCliOptions{}
+		_, err := newApp(opts, &fakeCliOptions{}).Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "flag-user", opts.Username)
+		assert.Equal(t, "env-pass", opts.Password)
+	})
+
+	t.Run("should load the options with the args set on the command", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		opts := &fakeCliOptions{}
+		app := newApp(opts, &fakeCliOptions{})
+		app.Command().SetArgs([]string{"sub1"})
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "file-user", opts.Username)
+	})
+
+	t.Run("should read the config once the command runs", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte("sub1: [\n"), 0o600))
+
+		var out bytes.Buffer
+		var exitErr error
+		execute := func(args ...string) error {
+			app := jcli.New("simple",
+				jcli.WithBaseName("testApp"),
+				jcli.EnableSilence(),
+				jcli.DisableVersion(),
+			)
+			sub1 := jcli.NewCommand("sub1", "sub1 command description",
+				jcli.WithCommandCliOptions(&fakeCliOptions{}),
+				jcli.WithCommandOnExit(func(ctx context.Context, err error) {
+					exitErr = err
+				}),
+			)
+			sub1.CobraCommand().SetOut(&out)
+			app.AddCommands(sub1)
+			app.Command().SetArgs(args)
+			_, err := app.Execute(context.Background())
+			return err
+		}
+
+		assert.NoError(t, execute("sub1", "--help"))
+		assert.Contains(t, out.String(), "sub1 command description")
+
+		err := execute("sub1")
+		assert.ErrorContains(t, err, "failed to read configuration file")
+		assert.Equal(t, err, exitErr)
+	})
+}
+
+func TestAppViper(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	err := os.WriteFile("testApp.yaml", []byte("username: file-user\n"), 0o600)
+	assert.NoError(t, err)
+
+	t.Run("should use the viper instance of the App", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		v := viper.New()
+		opts := &fakeCliOptions{}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(opts),
+			jcli.WithViper(v),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Same(t, v, app.Viper())
+		assert.Equal(t, "file-user", opts.Username)
+		assert.Equal(t, "file-user", v.GetString("username"))
+		assert.Empty(t, viper.GetString("username"))
+	})
+
+	t.Run("Apps should be isolated", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		t.Setenv("TESTAPP_PASSWORD", "env-pass")
+		app1 := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(&fakeCliOptions{}),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		opts := &fakeCliOptions{}
+		app2 := jcli.New("other",
+			jcli.WithBaseName("otherApp"),
+			jcli.WithCliOptions(opts),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		)
+		_, err := app1.Execute(context.Background())
+		assert.NoError(t, err)
+		_, err = app2.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.NotSame(t, app1.Viper(), app2.Viper())
+		assert.Equal(t, "file-user", app1.Viper().GetString("username"))
+		assert.Equal(t, "env-pass", app1.Viper().GetString("password"))
+		assert.Empty(t, opts.Username)
+		assert.Empty(t, opts.Password)
+	})
+
+	t.Run("should read the config along with the persistent pre-run of the commands", func(t *testing.T) {
+		var called []string
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+			jcli.WithRunFunc(func() error {
+				called = append(called, "root")
+				return nil
+			}),
+		)
+		app.Command().PersistentPreRun = func(cmd *cobra.Command, args []string) {
+			called = append(called, "pre-run")
+		}
+		var username string
+		app.AddCobraCommands(&cobra.Command{
+			Use: "sub",
+			PersistentPreRun: func(cmd *cobra.Command, args []string) {
+				called = append(called, "sub pre-run")
+			},
+			Run: func(cmd *cobra.Command, args []string) {
+				username = app.Viper().GetString("username")
+			},
+		})
+
+		os.Args = []string{"testApp"}
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"pre-run", "root"}, called)
+		assert.Equal(t, "file-user", app.Viper().GetString("username"))
+
+		os.Args = []string{"testApp", "sub"}
+		_, err = app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"pre-run", "root", "sub pre-run"}, called)
+		assert.Equal(t, "file-user", username)
+	})
+}
+
+func TestAppConfigFlag(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	assert.NoError(t, os.WriteFile("testApp.yaml", []byte("username: search-user\n"), 0o600))
+	assert.NoError(t, os.WriteFile("custom.yaml", []byte("username: custom-user\n"), 0o600))
+
+	newApp := func(opts *fakeCliOptions, options ...jcli.Option) *jcli.App {
+		return jcli.New("simple", append([]jcli.Option{
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(opts),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		}, options...)...)
+	}
+
+	t.Run("should not register the global config flag", func(t *testing.T) {
+		assert.Nil(t, pflag.Lookup(jcli.ConfigFlagName))
+	})
+
+	t.Run("should read the file specified by the config flag", func(t *testing.T) {
+		os.Args = []string{"testApp", "-c", "custom.yaml"}
+		opts := &fakeCliOptions{}
+		_, err := newApp(opts).Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "custom-user", opts.Username)
+	})
+
+	t.Run("should return the error of the config file", func(t *testing.T) {
+		os.Args = []string{"testApp", "--config", "missing.yaml"}
+		code, err := newApp(&fakeCliOptions{}).Execute(context.Background())
+		assert.Equal(t, jcli.ExitCodeError, code)
+		assert.ErrorContains(t, err, "failed to read configuration file(missing.yaml)")
+	})
+
+	t.Run("should use the custom config flag", func(t *testing.T) {
+		os.Args = []string{"testApp", "-k", "custom.yaml"}
+		opts := &fakeCliOptions{}
+		app := newApp(opts,
+			jcli.WithConfigFlag("kubeconfig", "k"),
+			jcli.WithConfigFlagUsage("Path to the kubeconfig `FILE`."),
+		)
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "custom-user", opts.Username)
+		flag := app.Command().Flags().Lookup("kubeconfig")
+		assert.NotNil(t, flag)
+		assert.Equal(t, "Path to the kubeconfig `FILE`.", flag.Usage)
+		assert.Nil(t, app.Command().Flags().Lookup(jcli.ConfigFlagName))
+	})
+
+	t.Run("sub commands should inherit the config flag", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile("sub.yaml", []byte(`
+username: root-user
+sub1:
+  username: sub1-user
+`), 0o600))
+		for _, args := range [][]string{
+			{"--config", "sub.yaml", "sub1"},
+			{"sub1", "--config", "sub.yaml"},
+			{"sub1", "-c", "sub.yaml"},
+		} {
+			root, sub1 := &fakeCliOptions{}, &fakeCliOptions{}
+			app := newApp(root, jcli.EnablePersistentCliOptions())
+			app.AddCommands(jcli.NewCommand("sub1", "sub1 command description",
+				jcli.WithCommandCliOptions(sub1),
+			))
+			app.Command().SetArgs(args)
+			_, err := app.Execute(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "root-user", root.Username)
+			assert.Equal(t, "sub1-user", sub1.Username)
+		}
+	})
+
+	t.Run("should still bind the config without the config flag", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		opts := &fakeCliOptions{}
+		app := newApp(opts, jcli.DisableConfigFlag())
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "search-user", opts.Username)
+		assert.Nil(t, app.Command().Flags().Lookup(jcli.ConfigFlagName))
+	})
+}
+
+func TestAppConfigSearchPaths(t *testing.T) {
+	writeConfig := func(t *testing.T, dir, username string) {
+		assert.NoError(t, os.MkdirAll(dir, 0o750))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "testApp.yaml"), []byte("username: "+username+"\n"), 0o600))
+	}
+	execute := func(t *testing.T, options ...jcli.Option) *fakeCliOptions {
+		os.Args = []string{"testApp"}
+		opts := &fakeCliOptions{}
+		app := jcli.New("simple", append([]jcli.Option{
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(opts),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+		}, options...)...)
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		return opts
+	}
+
+	t.Run("should search the home dir of a single-word basename", func(t *testing.T) {
+		home, _, _ := isolateConfigDirs(t)
+		writeConfig(t, filepath.Join(home, ".testApp"), "home-user")
+		assert.Equal(t, "home-user", execute(t).Username)
+	})
+
+	t.Run("should search the XDG dirs", func(t *testing.T) {
+		home, configHome, configDir := isolateConfigDirs(t)
+		writeConfig(t, filepath.Join(configDir, "testApp"), "xdg-dirs-user")
+		assert.Equal(t, "xdg-dirs-user", execute(t).Username)
+
+		writeConfig(t, filepath.Join(home, ".testApp"), "home-user")
+		assert.Equal(t, "home-user", execute(t).Username)
+
+		writeConfig(t, filepath.Join(configHome, "testApp"), "xdg-home-user")
+		assert.Equal(t, "xdg-home-user", execute(t).Username)
+	})
+
+	t.Run("should search the XDG dirs named by the basename", func(t *testing.T) {
+		home, configHome, configDir := isolateConfigDirs(t)
+		write := func(dir, username string) {
+			assert.NoError(t, os.MkdirAll(dir, 0o750))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "testApp-server.yaml"),
+				[]byte("username: "+username+"\n"), 0o600))
+		}
+		server := jcli.WithBaseName("testApp-server")
+		write(filepath.Join(configDir, "testApp"), "xdg-dirs-prefix-user")
+		assert.Empty(t, execute(t, server).Username)
+
+		write(filepath.Join(configDir, "testApp-server"), "xdg-dirs-user")
+		assert.Equal(t, "xdg-dirs-user", execute(t, server).Username)
+
+		write(filepath.Join(home, ".testApp"), "home-user")
+		assert.Equal(t, "home-user", execute(t, server).Username)
+
+		write(filepath.Join(configHome, "testApp-server"), "xdg-home-user")
+		assert.Equal(t, "xdg-home-user", execute(t, server).Username)
+	})
+
+	t.Run("should use the custom search paths", func(t *testing.T) {
+		home, _, _ := isolateConfigDirs(t)
+		writeConfig(t, filepath.Join(home, ".testApp"), "home-user")
+		custom := t.TempDir()
+		writeConfig(t, custom, "custom-user")
+		assert.Equal(t, "custom-user", execute(t, jcli.WithConfigSearchPaths(custom, home)).Username)
+		assert.Empty(t, execute(t, jcli.WithConfigSearchPaths(t.TempDir())).Username)
+	})
+}
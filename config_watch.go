@@ -0,0 +1,223 @@
+package jcli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/shipengqi/errors"
+)
+
+// configReloadDelay merges the bursts of file events, editors usually write a
+// file in several steps.
+const configReloadDelay = 100 * time.Millisecond
+
+// ConfigChangeFunc defines the callback function which is invoked when the
+// configuration is reloaded, old and new are the CliOptions before and after
+// the reload.
+type ConfigChangeFunc func(old, new CliOptions)
+
+// configWatcher reloads the options when the configuration files change or
+// SIGHUP arrives.
+type configWatcher struct {
+	onChange ConfigChangeFunc
+	// mu serializes the reloads
+	mu sync.Mutex
+}
+
+// watchConfig starts watching the configuration files used by the App, the
+// returned function stops watching.
+func (a *App) watchConfig() (stop func()) {
+	unregister := a.signals.register(syscall.SIGHUP, func(os.Signal) {
+		a.logger.Infof("%s Received signal SIGHUP, reloading configuration", progressMessage)
+		a.reloadConfig()
+	})
+
+	files := make(map[string]bool)
+	for _, file := range a.configFilesUsed() {
+		if abs, err := filepath.Abs(file); err == nil {
+			files[abs] = true
+		}
+	}
+	if len(files) == 0 {
+		return unregister
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		a.logger.Warnf("%s Failed to watch configuration: %v", progressMessage, err)
+		return unregister
+	}
+	// watch the directories, so the files replaced by the editors are still
+	// watched
+	for file := range files {
+		if err = watcher.Add(filepath.Dir(file)); err != nil {
+			a.logger.Warnf("%s Failed to watch configuration %s: %v", progressMessage, file, err)
+		}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var timer *time.Timer
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !files[filepath.Clean(event.Name)] || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(configReloadDelay, a.reloadConfig)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				a.logger.Warnf("%s Failed to watch configuration: %v", progressMessage, err)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		unregister()
+		close(done)
+		_ = watcher.Close()
+		wg.Wait()
+	}
+}
+
+// reloadConfig reads the configuration into a copy of the options, the copy
+// replaces the options only if it is completed and validated.
+func (a *App) reloadConfig() {
+	a.configWatcher.mu.Lock()
+	defer a.configWatcher.mu.Unlock()
+
+	if err := a.readConfig(a.basename); err != nil {
+		a.logger.Errorf("%s Failed to reload configuration: %v", progressMessage, err)
+		return
+	}
+	if err := a.checkConfigKeys(); err != nil {
+		a.logger.Errorf("%s Invalid configuration is ignored: %v", progressMessage, err)
+		return
+	}
+
+	old := a.Options()
+	fresh, ok := copyOptions(old)
+	if !ok {
+		a.logger.Errorf("%s Failed to reload configuration: the options must be a pointer to a struct", progressMessage)
+		return
+	}
+	if err := a.viper.Unmarshal(fresh); err != nil {
+		a.logger.Errorf("%s Failed to reload configuration: %v", progressMessage, err)
+		return
+	}
+	if err := completeAndValidate(fresh); err != nil {
+		a.logger.Errorf("%s Invalid configuration is ignored: %v", progressMessage, err)
+		return
+	}
+
+	a.optsMu.Lock()
+	a.opts = fresh
+	a.optsMu.Unlock()
+	a.logger.Infof("%s Configuration reloaded", progressMessage)
+	a.configWatcher.onChange(old, fresh)
+}
+
+// copyOptions returns a deep copy of the options, so that the reloaded values
+// are not shared with the options in use. It returns false if the options are
+// not a pointer to a struct.
+func copyOptions(opts CliOptions) (CliOptions, bool) {
+	v := reflect.ValueOf(opts)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	cp, ok := deepCopy(v).Interface().(CliOptions)
+	return cp, ok
+}
+
+// deepCopy copies the pointers, the maps, the slices and the arrays
+// recursively. The unexported fields of the structs are copied as is.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Elem().Type())
+		cp.Elem().Set(deepCopy(v.Elem()))
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(deepCopy(v.Elem()))
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := cp.Field(i); field.CanSet() {
+				field.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return cp
+	default:
+		return v
+	}
+}
+
+// completeAndValidate completes and validates the options.
+func completeAndValidate(opts CliOptions) error {
+	if options, ok := opts.(CompletableOptions); ok {
+		if err := options.Complete(); err != nil {
+			return err
+		}
+	}
+	if errs := opts.Validate(); len(errs) != 0 {
+		return errors.NewAggregate(errs)
+	}
+	return nil
+}
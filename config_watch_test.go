@@ -0,0 +1,152 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+type configChange struct {
+	old, new jcli.CliOptions
+}
+
+type serverOptions struct {
+	Port int `mapstructure:"port"`
+}
+
+type serverCliOptions struct {
+	Server *serverOptions `mapstructure:"server"`
+}
+
+func (o *serverCliOptions) Flags() (fss cliflag.NamedFlagSets) {
+	fs := fss.FlagSet("server")
+	fs.IntVar(&o.Server.Port, "server.port", o.Server.Port, "server port.")
+	return fss
+}
+
+func (o *serverCliOptions) Validate() []error {
+	if o.Server.Port < 1 || o.Server.Port > 65535 {
+		return []error{fmt.Errorf("invalid port %d", o.Server.Port)}
+	}
+	return nil
+}
+
+func TestAppConfigWatch(t *testing.T) {
+	setup := func(t *testing.T, opts jcli.CliOptions, config string) (
+		*jcli.App, *jcli.SignalInjector, chan configChange, *bytes.Buffer, func()) {
+		t.Chdir(t.TempDir())
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte(config), 0o600))
+
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		changes := make(chan configChange, 1)
+		running := make(chan struct{})
+		injector := jcli.NewSignalInjector()
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(opts),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.WithSignalSource(injector),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+			jcli.EnableConfigWatch(func(old, new jcli.CliOptions) {
+				changes <- configChange{old, new}
+			}),
+			jcli.WithRunContextFunc(func(ctx context.Context) error {
+				close(running)
+				<-ctx.Done()
+				return nil
+			}),
+		)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := app.Execute(context.Background())
+			assert.NoError(t, err)
+		}()
+		<-running
+		return app, injector, changes, &buf, func() {
+			injector.Send(syscall.SIGTERM)
+			<-done
+		}
+	}
+
+	t.Run("should reload the changed config file", func(t *testing.T) {
+		app, _, changes, _, stop := setup(t, &completableCliOptions{}, "username: before\n")
+		defer stop()
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte("username: after\n"), 0o600))
+
+		select {
+		case change := <-changes:
+			assert.Equal(t, "before", change.old.(*completableCliOptions).Username)
+			assert.Equal(t, "after", change.new.(*completableCliOptions).Username)
+			assert.True(t, change.new.(*completableCliOptions).completed)
+			assert.Same(t, change.new, app.Options())
+		case <-time.After(5 * time.Second):
+			t.Fatal("the configuration is not reloaded")
+		}
+	})
+
+	t.Run("should ignore the invalid config", func(t *testing.T) {
+		app, injector, changes, buf, stop := setup(t, &completableCliOptions{}, "username: before\n")
+		defer stop()
+		old := app.Options()
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte("username: \"\"\n"), 0o600))
+		// the reload is complete once the signal is delivered
+		assert.True(t, injector.Send(syscall.SIGHUP))
+
+		select {
+		case <-changes:
+			t.Fatal("the invalid configuration should be ignored")
+		default:
+		}
+		assert.Same(t, old, app.Options())
+		assert.Contains(t, buf.String(), "Invalid configuration is ignored: username is required")
+	})
+
+	t.Run("should not share the nested options with the reloaded ones", func(t *testing.T) {
+		app, injector, changes, buf, stop := setup(t, &serverCliOptions{Server: &serverOptions{}}, "server:\n  port: 8080\n")
+		defer stop()
+		old := app.Options().(*serverCliOptions)
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte("server:\n  port: 99999\n"), 0o600))
+		assert.True(t, injector.Send(syscall.SIGHUP))
+
+		assert.Contains(t, buf.String(), "Invalid configuration is ignored: invalid port 99999")
+		assert.Same(t, old, app.Options())
+		assert.Equal(t, 8080, old.Server.Port)
+
+		assert.NoError(t, os.WriteFile("testApp.yaml", []byte("server:\n  port: 9090\n"), 0o600))
+		select {
+		case change := <-changes:
+			assert.Same(t, old, change.old)
+			assert.Equal(t, 8080, old.Server.Port)
+			assert.Equal(t, 9090, change.new.(*serverCliOptions).Server.Port)
+			assert.NotSame(t, old.Server, change.new.(*serverCliOptions).Server)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the configuration is not reloaded")
+		}
+	})
+
+	t.Run("should reload on SIGHUP", func(t *testing.T) {
+		_, injector, changes, _, stop := setup(t, &completableCliOptions{}, "username: before\n")
+		defer stop()
+		t.Setenv("TESTAPP_USERNAME", "env")
+		assert.True(t, injector.Send(syscall.SIGHUP))
+
+		select {
+		case change := <-changes:
+			assert.Equal(t, "env", change.new.(*completableCliOptions).Username)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the configuration is not reloaded")
+		}
+	})
+}
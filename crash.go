@@ -0,0 +1,128 @@
+package jcli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/shipengqi/component-base/version"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ExitCodePanic is the exit code when a panic is recovered.
+const ExitCodePanic = 2
+
+const redactedValue = "******"
+
+// sensitiveKeywords are the keywords of the sensitive flag names, whose values
+// are redacted.
+var sensitiveKeywords = []string{"password", "passwd", "secret", "token", "credential", "key"}
+
+// PanicError is the error converted from a recovered panic.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+	// Report is the path of the crash report, it is empty if the report is
+	// failed to write.
+	Report string
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	if e.Report == "" {
+		return fmt.Sprintf("panic: %v", e.Value)
+	}
+	return fmt.Sprintf("panic: %v, a crash report has been written to %s", e.Value, e.Report)
+}
+
+// recoverer converts panics into errors and writes crash reports.
+type recoverer struct {
+	enabled bool
+	dir     string
+}
+
+// call invokes fn, if the recovery is enabled, a panic inside fn is converted
+// into an ExitError with ExitCodePanic which wraps a PanicError.
+func (r *recoverer) call(cmd *cobra.Command, fn func() error) (err error) {
+	if !r.enabled {
+		return fn()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			err = r.recovered(cmd, v, debug.Stack())
+		}
+	}()
+	return fn()
+}
+
+func (r *recoverer) recovered(cmd *cobra.Command, v interface{}, stack []byte) error {
+	perr := &PanicError{Value: v, Stack: stack}
+	if report, err := r.writeReport(cmd, perr); err == nil {
+		perr.Report = report
+	}
+	return NewExitError(ExitCodePanic, perr)
+}
+
+// writeReport writes the crash report to the crash report directory, defaults
+// to the temp directory. It returns the path of the report.
+func (r *recoverer) writeReport(cmd *cobra.Command, perr *PanicError) (string, error) {
+	dir := r.dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, fmt.Sprintf("%s-crash-%s-*.log",
+		cmd.Root().Name(), time.Now().Format("20060102150405")))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err = f.Write(crashReport(cmd, perr)); err != nil {
+		return "", err
+	}
+	return filepath.Clean(f.Name()), nil
+}
+
+func crashReport(cmd *cobra.Command, perr *PanicError) []byte {
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "Time: %s\n", time.Now().Format(time.RFC3339))
+	_, _ = fmt.Fprintf(&buf, "Command: %s\n", cmd.CommandPath())
+	_, _ = fmt.Fprintf(&buf, "\nVersion:\n%s\n", version.Get().String())
+	_, _ = fmt.Fprint(&buf, "\nFlags:\n")
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		_, _ = fmt.Fprintf(&buf, "  --%s=%q\n", flag.Name, redactFlagValue(flag))
+	})
+	_, _ = fmt.Fprintf(&buf, "\nPanic: %v\n", perr.Value)
+	_, _ = fmt.Fprintf(&buf, "\nStack:\n%s\n", perr.Stack)
+	return buf.Bytes()
+}
+
+// isSensitiveKey reports whether the flag or the config key may hold a
+// sensitive value.
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, v := range sensitiveKeywords {
+		if strings.Contains(key, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactFlagValue(flag *pflag.Flag) string {
+	value := flag.Value.String()
+	if value != "" && isSensitiveKey(flag.Name) {
+		return redactedValue
+	}
+	return value
+}
@@ -0,0 +1,123 @@
+package jcli_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func TestRecover(t *testing.T) {
+	t.Run("should convert panics into errors and write crash reports", func(t *testing.T) {
+		os.Args = []string{"testApp", "--username", "admin", "--password", "123456"}
+		dir := t.TempDir()
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(&fakeCliOptions{}),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.EnableRecover(),
+			jcli.WithCrashReportDir(dir),
+			jcli.WithRunFunc(func() error {
+				panic("something wrong")
+			}),
+		)
+		code, err := app.Execute(context.Background())
+		assert.Equal(t, jcli.ExitCodePanic, code)
+
+		var perr *jcli.PanicError
+		assert.True(t, errors.As(err, &perr))
+		assert.Equal(t, "something wrong", perr.Value)
+		assert.Contains(t, err.Error(), "panic: something wrong, a crash report has been written to")
+		assert.FileExists(t, perr.Report)
+
+		report, _ := os.ReadFile(perr.Report)
+		assert.Contains(t, string(report), "Command: testApp")
+		assert.Contains(t, string(report), "Version:")
+		assert.Contains(t, string(report), `--username="admin"`)
+		assert.Contains(t, string(report), `--password="******"`)
+		assert.NotContains(t, string(report), "123456")
+		assert.Contains(t, string(report), "Stack:")
+	})
+
+	t.Run("should recover panics in the services", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.EnableRecover(),
+			jcli.WithCrashReportDir(t.TempDir()),
+		)
+		app.AddService("worker", func(ctx context.Context) error {
+			panic("worker crashed")
+		})
+		code, err := app.Execute(context.Background())
+		assert.Equal(t, jcli.ExitCodePanic, code)
+		assert.Contains(t, err.Error(), "panic: worker crashed")
+	})
+
+	t.Run("should recover panics in the command", func(t *testing.T) {
+		os.Args = []string{"simplecmd"}
+		cmd := jcli.NewCommand("simplecmd", "this is a test command",
+			jcli.EnableCommandRecover(),
+			jcli.WithCommandCrashReportDir(t.TempDir()),
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				panic("command crashed")
+			}),
+		)
+		code, err := cmd.Execute(context.Background())
+		assert.Equal(t, jcli.ExitCodePanic, code)
+		assert.Contains(t, err.Error(), "panic: command crashed")
+	})
+
+	t.Run("should recover panics in the sub commands of the App", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub"}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.EnableRecover(),
+			jcli.WithCrashReportDir(t.TempDir()),
+		)
+		app.AddCommands(jcli.NewCommand("sub", "this is a sub command",
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				panic("sub command crashed")
+			}),
+		))
+		code, err := app.Execute(context.Background())
+		assert.Equal(t, jcli.ExitCodePanic, code)
+		assert.Contains(t, err.Error(), "panic: sub command crashed")
+	})
+
+	t.Run("should recover panics in the hooks", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		ran := false
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.EnableRecover(),
+			jcli.WithCrashReportDir(t.TempDir()),
+			jcli.WithPreRun(func(ctx context.Context, args []string) error {
+				panic("hook crashed")
+			}),
+			jcli.WithRunFunc(func() error {
+				ran = true
+				return nil
+			}),
+		)
+		code, err := app.Execute(context.Background())
+		assert.Equal(t, jcli.ExitCodePanic, code)
+		assert.Contains(t, err.Error(), "panic: hook crashed")
+		assert.False(t, ran)
+	})
+}
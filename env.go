@@ -0,0 +1,156 @@
+package jcli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// FlagEnvAnnotation is the annotation of a flag which overrides the name
+	// of its environment variable.
+	FlagEnvAnnotation = "jcli_env"
+	// FlagEnvAliasesAnnotation is the annotation of a flag which holds the
+	// aliases of its environment variable, e.g. the legacy names.
+	FlagEnvAliasesAnnotation = "jcli_env_aliases"
+)
+
+// envKeyReplacer converts a key to the suffix of its environment variable,
+// e.g. "server.port" to "server_port".
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// SetFlagEnv overrides the name of the environment variable of the flag. It
+// defaults to the name derived from the basename and the flag name, e.g.
+// DEMO_USERNAME for the flag "username" of the App "demo".
+func SetFlagEnv(fs *pflag.FlagSet, name, env string) error {
+	return fs.SetAnnotation(name, FlagEnvAnnotation, []string{env})
+}
+
+// AddFlagEnvAliases adds the aliases of the environment variable of the flag,
+// they are checked in order when the environment variable is not set.
+func AddFlagEnvAliases(fs *pflag.FlagSet, name string, aliases ...string) error {
+	flag := fs.Lookup(name)
+	if flag == nil {
+		return fmt.Errorf("flag %q does not exist", name)
+	}
+	return fs.SetAnnotation(name, FlagEnvAliasesAnnotation,
+		append(flag.Annotations[FlagEnvAliasesAnnotation], aliases...))
+}
+
+// flagEnvNames returns the environment variables of the flag, the first one
+// takes precedence.
+func flagEnvNames(prefix string, f *pflag.Flag) []string {
+	name := prefix + "_" + strings.ToUpper(envKeyReplacer.Replace(f.Name))
+	if envs := f.Annotations[FlagEnvAnnotation]; len(envs) > 0 && envs[0] != "" {
+		name = envs[0]
+	}
+	return append([]string{name}, f.Annotations[FlagEnvAliasesAnnotation]...)
+}
+
+// envReplacer maps the keys to their environment variables, e.g.
+// "DEMO_SERVER.PORT" to "DEMO_SERVER_PORT". The variables overridden by
+// SetFlagEnv replace the derived ones.
+type envReplacer struct {
+	overrides map[string]string
+}
+
+// Replace implements the viper.StringReplacer interface.
+func (r *envReplacer) Replace(s string) string {
+	if env, ok := r.overrides[s]; ok {
+		return env
+	}
+	return envKeyReplacer.Replace(s)
+}
+
+// bindFlags binds the flags to their environment variables. The overridden
+// variables are looked up instead of the derived ones, the aliases are checked
+// after them.
+func (r *envReplacer) bindFlags(v *viper.Viper, prefix string, fs *pflag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *pflag.Flag) {
+		envs := flagEnvNames(prefix, f)
+		// viper looks up the upper-cased key with the prefix
+		derived := strings.ToUpper(prefix + "_" + f.Name)
+		overridden := envs[0] != envKeyReplacer.Replace(derived)
+		if overridden {
+			if r.overrides == nil {
+				r.overrides = make(map[string]string)
+			}
+			r.overrides[derived] = envs[0]
+		}
+		// the overridden variable is bound as well, for the viper which is
+		// not created with the envReplacer
+		if err == nil && (overridden || len(envs) > 1) {
+			err = v.BindEnv(append([]string{f.Name}, envs...)...)
+		}
+	})
+	return err
+}
+
+// commandEnvPrefix returns the prefix of the environment variables of the
+// command, e.g. "DEMO_SUB1" for "demo sub1".
+func commandEnvPrefix(basename string, cmd *cobra.Command) string {
+	path := strings.Fields(cmd.CommandPath())[1:]
+	return envPrefix(strings.Join(append([]string{basename}, path...), "_"))
+}
+
+// flagEnvs holds the environment variables of the flags which are bound to
+// the configuration.
+type flagEnvs map[*pflag.Flag][]string
+
+func (e flagEnvs) add(prefix string, fss cliflag.NamedFlagSets) {
+	for _, name := range fss.Order {
+		if name == FlagSetNameGlobal {
+			continue
+		}
+		fss.FlagSets[name].VisitAll(func(f *pflag.Flag) {
+			e[f] = flagEnvNames(prefix, f)
+		})
+	}
+}
+
+// sections returns a copy of the flag sections, the usages of the flags are
+// appended with their environment variables, e.g. "[$DEMO_USERNAME]".
+func (e flagEnvs) sections(fss cliflag.NamedFlagSets) cliflag.NamedFlagSets {
+	if len(e) == 0 {
+		return fss
+	}
+	var sections cliflag.NamedFlagSets
+	for _, name := range fss.Order {
+		fs := sections.FlagSet(name)
+		fss.FlagSets[name].VisitAll(func(f *pflag.Flag) {
+			envs, ok := e[f]
+			if !ok {
+				fs.AddFlag(f)
+				return
+			}
+			flag := *f
+			flag.Usage = fmt.Sprintf("%s [$%s]", f.Usage, strings.Join(envs, ", $"))
+			fs.AddFlag(&flag)
+		})
+	}
+	return sections
+}
+
+// print prints the "Environment" section of the flags in the sections.
+func (e flagEnvs) print(w io.Writer, fss cliflag.NamedFlagSets) {
+	if len(e) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "\nEnvironment:\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+	for _, name := range fss.Order {
+		fss.FlagSets[name].VisitAll(func(f *pflag.Flag) {
+			if envs, ok := e[f]; ok && !f.Hidden {
+				_, _ = fmt.Fprintf(tw, "  %s\t--%s\n", strings.Join(envs, ", "), f.Name)
+			}
+		})
+	}
+	_ = tw.Flush()
+}
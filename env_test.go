@@ -0,0 +1,121 @@
+package jcli_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+type envCliOptions struct {
+	Username string
+	Password string
+	Server   struct {
+		Port int
+	}
+}
+
+func (o *envCliOptions) Flags() (fss cliflag.NamedFlagSets) {
+	fs := fss.FlagSet("env")
+	fs.StringVar(&o.Username, "username", o.Username, "env username.")
+	fs.StringVar(&o.Password, "password", o.Password, "env password.")
+	fs.IntVar(&o.Server.Port, "server.port", 8080, "env port.")
+	_ = jcli.AddFlagEnvAliases(fs, "username", "TESTAPP_USER", "USER_NAME")
+	_ = jcli.SetFlagEnv(fs, "server.port", "PORT")
+	return fss
+}
+
+func (o *envCliOptions) Validate() []error {
+	return nil
+}
+
+func TestAppFlagEnvs(t *testing.T) {
+	execute := func(t *testing.T, opts jcli.CliOptions, args ...string) (string, error) {
+		isolateConfigDirs(t)
+		r, w, _ := os.Pipe()
+		tmp := os.Stdout
+		defer func() {
+			os.Stdout = tmp
+		}()
+		os.Stdout = w
+		os.Args = append([]string{"testApp"}, args...)
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(opts),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+			jcli.WithRunFunc(func() error { return nil }),
+		)
+		app.AddCommands(jcli.NewCommand("sub1", "sub1 command",
+			jcli.WithCommandCliOptions(&fakeCliOptions{}),
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error { return nil }),
+		))
+		_, err := app.Execute(context.Background())
+		_ = w.Close()
+		stdout, _ := io.ReadAll(r)
+		return string(stdout), err
+	}
+
+	t.Run("help should show the environment variables", func(t *testing.T) {
+		stdout, err := execute(t, &envCliOptions{}, "--help")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "--password string   env password. [$TESTAPP_PASSWORD]\n")
+		assert.Contains(t, stdout, "--server.port int   env port. [$PORT] (default 8080)\n")
+		assert.Contains(t, stdout, "--username string   env username. [$TESTAPP_USERNAME, $TESTAPP_USER, $USER_NAME]\n")
+		assert.Contains(t, stdout, `Environment:
+  TESTAPP_PASSWORD                            --password
+  PORT                                        --server.port
+  TESTAPP_USERNAME, TESTAPP_USER, USER_NAME   --username
+`)
+		assert.NotContains(t, stdout, "$TESTAPP_CONFIG")
+	})
+
+	t.Run("sub command help should show the environment variables", func(t *testing.T) {
+		stdout, err := execute(t, &envCliOptions{}, "sub1", "--help")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "--username string   fake username. [$TESTAPP_SUB1_USERNAME]\n")
+		assert.Contains(t, stdout, "Environment:\n  TESTAPP_SUB1_PASSWORD   --password\n")
+	})
+
+	t.Run("the aliases should be checked after the derived variable", func(t *testing.T) {
+		opts := &envCliOptions{}
+		t.Setenv("USER_NAME", "name-user")
+		t.Setenv("TESTAPP_USER", "alias-user")
+		_, err := execute(t, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "alias-user", opts.Username)
+
+		t.Setenv("TESTAPP_USERNAME", "derived-user")
+		_, err = execute(t, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "derived-user", opts.Username)
+	})
+
+	t.Run("the overridden variable should replace the derived one", func(t *testing.T) {
+		opts := &envCliOptions{}
+		t.Setenv("TESTAPP_SERVER_PORT", "1")
+		_, err := execute(t, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 8080, opts.Server.Port)
+
+		t.Setenv("PORT", "9090")
+		_, err = execute(t, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 9090, opts.Server.Port)
+
+		_, err = execute(t, opts, "--server.port", "7070")
+		assert.NoError(t, err)
+		assert.Equal(t, 7070, opts.Server.Port)
+	})
+
+	t.Run("AddFlagEnvAliases should fail if the flag does not exist", func(t *testing.T) {
+		fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		assert.ErrorContains(t, jcli.AddFlagEnvAliases(fs, "missing", "MISSING"), "flag \"missing\" does not exist")
+	})
+}
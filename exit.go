@@ -0,0 +1,91 @@
+package jcli
+
+import (
+	"fmt"
+
+	"github.com/shipengqi/errors"
+)
+
+const (
+	// ExitCodeOK is the exit code of a successful execution.
+	ExitCodeOK = 0
+	// ExitCodeError is the default exit code of a failed execution.
+	ExitCodeError = 1
+)
+
+// ExitError is an error that carries the exit code of the application.
+// If Err is nil, the error is considered already reported, and Run exits
+// with Code without printing anything.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// NewExitError creates a new ExitError with the given exit code and error.
+func NewExitError(code int, err error) *ExitError {
+	return &ExitError{Code: code, Err: err}
+}
+
+// Error implements the error interface.
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCodeMapper maps an error to an exit code, the ok reports whether the
+// error is matched.
+type ExitCodeMapper func(err error) (code int, ok bool)
+
+// exitCodes is a registry that maps errors to exit codes.
+type exitCodes struct {
+	mappers []ExitCodeMapper
+}
+
+// register maps the target error to the exit code, errors are matched with
+// errors.Is.
+func (r *exitCodes) register(target error, code int) {
+	r.mappers = append(r.mappers, func(err error) (int, bool) {
+		return code, errors.Is(err, target)
+	})
+}
+
+// registerMapper adds a mapper to the registry, it can be used to match errors
+// with errors.As.
+func (r *exitCodes) registerMapper(mapper ExitCodeMapper) {
+	r.mappers = append(r.mappers, mapper)
+}
+
+// code returns the exit code of the given error. An ExitError takes precedence
+// over the registered mappers, the mappers are checked in registration order.
+func (r *exitCodes) code(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	for _, m := range r.mappers {
+		if code, ok := m(err); ok {
+			return code
+		}
+	}
+	return ExitCodeError
+}
+
+// printError prints the error in the format of Run. An ExitError without
+// an underlying error is considered already reported.
+func printError(err error) {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.Err == nil {
+		return
+	}
+	fmt.Printf("%v %v\n", Red("Error:"), err)
+}
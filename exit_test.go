@@ -0,0 +1,85 @@
+package jcli_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+var errFakeNotFound = errors.New("fake not found")
+
+type fakeCodeError struct {
+	code int
+}
+
+func (e *fakeCodeError) Error() string {
+	return fmt.Sprintf("fake code error %d", e.code)
+}
+
+func TestExitError(t *testing.T) {
+	err := jcli.NewExitError(3, errFakeNotFound)
+	assert.Equal(t, "fake not found", err.Error())
+	assert.ErrorIs(t, err, errFakeNotFound)
+
+	err = jcli.NewExitError(4, nil)
+	assert.Equal(t, "exit status 4", err.Error())
+}
+
+func TestAppExecute(t *testing.T) {
+	tests := []struct {
+		title    string
+		err      error
+		expected int
+	}{
+		{"without error", nil, jcli.ExitCodeOK},
+		{"with unknown error", errors.New("unknown"), jcli.ExitCodeError},
+		{"with exit error", jcli.NewExitError(5, errors.New("exit")), 5},
+		{"with registered error", fmt.Errorf("wrapped: %w", errFakeNotFound), 3},
+		{"with mapped error", &fakeCodeError{code: 7}, 7},
+	}
+
+	for _, v := range tests {
+		t.Run(v.title, func(t *testing.T) {
+			os.Args = []string{"testApp"}
+			app := jcli.New("simple",
+				jcli.WithBaseName("testApp"),
+				jcli.EnableSilence(),
+				jcli.DisableConfig(),
+				jcli.DisableVersion(),
+				jcli.WithExitCode(errFakeNotFound, 3),
+				jcli.WithExitCodeMapper(func(err error) (int, bool) {
+					var target *fakeCodeError
+					if errors.As(err, &target) {
+						return target.code, true
+					}
+					return 0, false
+				}),
+				jcli.WithRunFunc(func() error {
+					return v.err
+				}),
+			)
+			code, err := app.Execute(context.Background())
+			assert.Equal(t, v.expected, code)
+			assert.Equal(t, v.err, err)
+		})
+	}
+}
+
+func TestCommandExecute(t *testing.T) {
+	os.Args = []string{"simplecmd"}
+	cmd := jcli.NewCommand("simplecmd", "this is a test command",
+		jcli.WithCommandExitCode(errFakeNotFound, 3),
+		jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+			return errFakeNotFound
+		}),
+	)
+	code, err := cmd.Execute(context.Background())
+	assert.Equal(t, 3, code)
+	assert.ErrorIs(t, err, errFakeNotFound)
+}
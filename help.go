@@ -5,17 +5,21 @@ import (
 	"strings"
 
 	"github.com/fatih/color"
+	cliflag "github.com/shipengqi/component-base/cli/flag"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
 const (
-	flagHelp = "help"
+	flagHelp    = "help"
+	commandHelp = "help"
+
+	usageFmt = "Usage:\n  %s\n"
 )
 
 func helpCommand(name string) *cobra.Command {
 	return &cobra.Command{
-		Use:   "help [command]",
+		Use:   commandHelp + " [command]",
 		Short: "Help about any command.",
 		Long: `Help provides help for any command in the application.
 Simply type ` + name + ` help [path to command] for full details.`,
@@ -40,3 +44,81 @@ func addHelpCommandFlag(usage string, fs *pflag.FlagSet) {
 		fmt.Sprintf("Help for the %s command.", color.GreenString(strings.Split(usage, " ")[0])),
 	)
 }
+
+// setUsageAndHelpFunc is similar to cliflag.SetUsageAndHelpFunc, but the flag
+// sections are resolved when the usage or help is printed, and the flags bound
+// to the environment variables are followed by the variables.
+func setUsageAndHelpFunc(cmd *cobra.Command, cols int,
+	sections func(cmd *cobra.Command) (cliflag.NamedFlagSets, flagEnvs)) {
+	cmd.SetUsageFunc(func(cmd *cobra.Command) error {
+		_, _ = fmt.Fprintf(cmd.OutOrStderr(), usageFmt, cmd.UseLine())
+		fss, envs := sections(cmd)
+		printCommandSections(cmd, fss, envs, cols)
+		return nil
+	})
+	cmd.SetHelpFunc(func(cmd *cobra.Command, _ []string) {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s\n\n"+usageFmt, cmd.Long, cmd.UseLine())
+		fss, envs := sections(cmd)
+		printCommandSections(cmd, fss, envs, cols)
+	})
+}
+
+// setUsageAndHelpFunc sets the usage and help func of the App, the flags of
+// the options are followed by their environment variables.
+func (a *App) setUsageAndHelpFunc(cmd *cobra.Command, nfs cliflag.NamedFlagSets, cols int) {
+	envs := make(flagEnvs)
+	if !a.disableConfig {
+		envs.add(envPrefix(a.basename), a.optsFlagSets)
+	}
+	setUsageAndHelpFunc(cmd, cols, func(*cobra.Command) (cliflag.NamedFlagSets, flagEnvs) {
+		return nfs, envs
+	})
+}
+
+// setUsageAndHelpFunc sets the usage and help func of the Command, the flags
+// inherited from the App are included.
+func (c *Command) setUsageAndHelpFunc(cmd *cobra.Command, nfs cliflag.NamedFlagSets, cols int) {
+	setUsageAndHelpFunc(cmd, cols, func(cmd *cobra.Command) (cliflag.NamedFlagSets, flagEnvs) {
+		return c.flagSections(nfs), c.flagEnvs(cmd, nfs)
+	})
+}
+
+func printCommandSections(cmd *cobra.Command, fss cliflag.NamedFlagSets, envs flagEnvs, cols int) {
+	cliflag.PrintAliases(cmd.OutOrStderr(), cmd)
+	cliflag.PrintSubCommands(cmd.OutOrStderr(), cmd)
+	cliflag.PrintSections(cmd.OutOrStderr(), envs.sections(fss), cols)
+	envs.print(cmd.OutOrStderr(), fss)
+	cliflag.PrintExamples(cmd.OutOrStderr(), cmd)
+	cliflag.PrintMore(cmd.OutOrStderr(), cmd)
+}
+
+// flagSections returns the flag sections of the Command, followed by the
+// persistent flag sections of the App.
+func (c *Command) flagSections(nfs cliflag.NamedFlagSets) cliflag.NamedFlagSets {
+	app := c.App()
+	if app == nil || !app.persistentOpts {
+		return nfs
+	}
+	var fss cliflag.NamedFlagSets
+	for _, sections := range []cliflag.NamedFlagSets{nfs, app.optsFlagSets} {
+		for _, name := range sections.Order {
+			fss.FlagSet(name).AddFlagSet(sections.FlagSets[name])
+		}
+	}
+	return fss
+}
+
+// flagEnvs returns the environment variables of the flags of the options of
+// the Command, and the persistent options of the App.
+func (c *Command) flagEnvs(cmd *cobra.Command, nfs cliflag.NamedFlagSets) flagEnvs {
+	envs := make(flagEnvs)
+	app := c.App()
+	if app == nil || app.disableConfig {
+		return envs
+	}
+	envs.add(commandEnvPrefix(app.basename, cmd), nfs)
+	if app.persistentOpts {
+		envs.add(envPrefix(app.basename), app.optsFlagSets)
+	}
+	return envs
+}
@@ -0,0 +1,126 @@
+package jcli
+
+import (
+	"context"
+)
+
+// HookFunc defines the lifecycle hook callback function which is invoked
+// before or after the run callback.
+type HookFunc func(ctx context.Context, args []string) error
+
+// ErrorHookFunc defines the callback function which is invoked when the
+// execution fails. The returned error replaces the original one, return nil
+// to swallow it.
+type ErrorHookFunc func(ctx context.Context, err error) error
+
+// ExitHookFunc defines the callback function which is always invoked when the
+// execution finishes, err is the final error of the execution.
+type ExitHookFunc func(ctx context.Context, err error)
+
+// hooks holds the lifecycle hooks of an App or a Command.
+//
+// The hooks are invoked in the following order:
+//  1. the persistent pre-run hooks, from the root down to the executing command
+//  2. the pre-run hooks of the executing command
+//  3. the run callback
+//  4. the post-run hooks of the executing command
+//  5. the persistent post-run hooks, from the executing command up to the root
+//
+// If any step fails, the remaining steps are skipped and the on-error hooks
+// are invoked. The on-exit hooks are always invoked at last. The on-error and
+// on-exit hooks are not inherited by the sub commands, but if the execution
+// finishes before the hooks of the executing command, e.g. on an unknown flag
+// or command, the hooks of the App or the root Command are invoked.
+type hooks struct {
+	preRuns            []HookFunc
+	postRuns           []HookFunc
+	persistentPreRuns  []HookFunc
+	persistentPostRuns []HookFunc
+	onErrors           []ErrorHookFunc
+	onExits            []ExitHookFunc
+}
+
+// execute runs the prepare and run functions wrapped by the lifecycle hooks.
+// The chain contains the hooks from the root to the executing command, its
+// last element must be h itself. The prepare and run functions and the pre-run
+// and post-run hooks are invoked through guard, e.g. to recover the panics.
+func (h *hooks) execute(ctx context.Context, chain []*hooks, args []string,
+	guard func(fn func() error) error, prepare, run func() error) (err error) {
+	defer func() {
+		err = h.finish(ctx, err)
+	}()
+
+	if prepare != nil {
+		if err = guard(prepare); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range chain {
+		if err = guard(runHooks(ctx, v.persistentPreRuns, args)); err != nil {
+			return err
+		}
+	}
+	if err = guard(runHooks(ctx, h.preRuns, args)); err != nil {
+		return err
+	}
+
+	if run != nil {
+		if err = guard(run); err != nil {
+			return err
+		}
+	}
+
+	if err = guard(runHooks(ctx, h.postRuns, args)); err != nil {
+		return err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if err = guard(runHooks(ctx, chain[i].persistentPostRuns, args)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finish invokes the on-error hooks if err is not nil, and then the on-exit
+// hooks, it returns the final error.
+func (h *hooks) finish(ctx context.Context, err error) error {
+	if finished, ok := ctx.Value(hooksFinishedKey{}).(*bool); ok {
+		*finished = true
+	}
+	if err != nil {
+		for _, fn := range h.onErrors {
+			if err = fn(ctx, err); err == nil {
+				break
+			}
+		}
+	}
+	for _, fn := range h.onExits {
+		fn(ctx, err)
+	}
+	return err
+}
+
+// hooksFinishedKey is the context key of the flag which records whether the
+// on-error and on-exit hooks of an execution are invoked.
+type hooksFinishedKey struct{}
+
+// withHooksFinished returns a copy of ctx which records whether the on-error
+// and on-exit hooks of the executing command are invoked.
+func withHooksFinished(ctx context.Context) (context.Context, *bool) {
+	finished := new(bool)
+	return context.WithValue(ctx, hooksFinishedKey{}, finished), finished
+}
+
+// runHooks returns a function which runs the hooks in order.
+func runHooks(ctx context.Context, fns []HookFunc, args []string) func() error {
+	return func() error {
+		for _, fn := range fns {
+			if err := fn(ctx, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,194 @@
+package jcli_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func recordHook(records *[]string, name string) jcli.HookFunc {
+	return func(ctx context.Context, args []string) error {
+		*records = append(*records, name)
+		return nil
+	}
+}
+
+func TestAppHooks(t *testing.T) {
+	t.Run("should invoke hooks in order", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var records []string
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithPersistentPreRun(recordHook(&records, "persistent-pre-run")),
+			jcli.WithPreRun(recordHook(&records, "pre-run")),
+			jcli.WithPostRun(recordHook(&records, "post-run")),
+			jcli.WithPersistentPostRun(recordHook(&records, "persistent-post-run")),
+			jcli.WithOnError(func(ctx context.Context, err error) error {
+				records = append(records, "on-error")
+				return err
+			}),
+			jcli.WithOnExit(func(ctx context.Context, err error) {
+				records = append(records, "on-exit")
+			}),
+			jcli.WithRunFunc(func() error {
+				records = append(records, "run")
+				return nil
+			}),
+		)
+		code, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.Equal(t, []string{
+			"persistent-pre-run", "pre-run", "run", "post-run", "persistent-post-run", "on-exit",
+		}, records)
+	})
+
+	t.Run("should invoke error hooks when run fails", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var records []string
+		var exitErr error
+		runErr := errors.New("run failed")
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithPreRun(recordHook(&records, "pre-run")),
+			jcli.WithPostRun(recordHook(&records, "post-run")),
+			jcli.WithOnError(func(ctx context.Context, err error) error {
+				records = append(records, "on-error")
+				return err
+			}),
+			jcli.WithOnExit(func(ctx context.Context, err error) {
+				records = append(records, "on-exit")
+				exitErr = err
+			}),
+			jcli.WithRunFunc(func() error {
+				records = append(records, "run")
+				return runErr
+			}),
+		)
+		_, err := app.Execute(context.Background())
+		assert.ErrorIs(t, err, runErr)
+		assert.ErrorIs(t, exitErr, runErr)
+		assert.Equal(t, []string{"pre-run", "run", "on-error", "on-exit"}, records)
+	})
+
+	t.Run("should swallow error in error hooks", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithOnError(func(ctx context.Context, err error) error {
+				return nil
+			}),
+			jcli.WithRunFunc(func() error {
+				return errors.New("run failed")
+			}),
+		)
+		code, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+	})
+
+	t.Run("should inherit persistent hooks", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub1", "sub1-sub1"}
+		var records []string
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithPersistentPreRun(recordHook(&records, "app-persistent-pre-run")),
+			jcli.WithPersistentPostRun(recordHook(&records, "app-persistent-post-run")),
+			jcli.WithPreRun(recordHook(&records, "app-pre-run")),
+		)
+		sub1 := jcli.NewCommand("sub1", "sub1 command description",
+			jcli.WithCommandPersistentPreRun(recordHook(&records, "sub1-persistent-pre-run")),
+			jcli.WithCommandPersistentPostRun(recordHook(&records, "sub1-persistent-post-run")),
+			jcli.WithCommandPreRun(recordHook(&records, "sub1-pre-run")),
+		)
+		sub1.AddCommands(jcli.NewCommand("sub1-sub1", "sub1-sub1 command description",
+			jcli.WithCommandPreRun(recordHook(&records, "sub1-sub1-pre-run")),
+			jcli.WithCommandPostRun(recordHook(&records, "sub1-sub1-post-run")),
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				records = append(records, "sub1-sub1-run")
+				return nil
+			}),
+		))
+		app.AddCommands(sub1)
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"app-persistent-pre-run",
+			"sub1-persistent-pre-run",
+			"sub1-sub1-pre-run",
+			"sub1-sub1-run",
+			"sub1-sub1-post-run",
+			"sub1-persistent-post-run",
+			"app-persistent-post-run",
+		}, records)
+	})
+
+	t.Run("should invoke error hooks when the flags fail to parse", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"testApp", "--unknown"},
+			{"testApp", "sub1", "--unknown"},
+		} {
+			os.Args = args
+			var records []string
+			var exitErr error
+			app := jcli.New("simple",
+				jcli.WithBaseName("testApp"),
+				jcli.EnableSilence(),
+				jcli.DisableConfig(),
+				jcli.DisableVersion(),
+				jcli.WithOnError(func(ctx context.Context, err error) error {
+					records = append(records, "on-error")
+					return err
+				}),
+				jcli.WithOnExit(func(ctx context.Context, err error) {
+					records = append(records, "on-exit")
+					exitErr = err
+				}),
+			)
+			app.AddCommands(jcli.NewCommand("sub1", "sub1 command description",
+				jcli.WithCommandOnExit(func(ctx context.Context, err error) {
+					records = append(records, "sub1-on-exit")
+				}),
+			))
+			code, err := app.Execute(context.Background())
+			assert.ErrorContains(t, err, "unknown flag: --unknown")
+			assert.Equal(t, jcli.ExitCodeError, code)
+			assert.Equal(t, err, exitErr)
+			assert.Equal(t, []string{"on-error", "on-exit"}, records)
+		}
+	})
+
+	t.Run("should invoke the hooks of the root command when the flags fail to parse", func(t *testing.T) {
+		var records []string
+		cmd := jcli.NewCommand("root", "root command description",
+			jcli.WithCommandOnError(func(ctx context.Context, err error) error {
+				records = append(records, "on-error")
+				return nil
+			}),
+			jcli.WithCommandOnExit(func(ctx context.Context, err error) {
+				records = append(records, "on-exit")
+			}),
+		)
+		cmd.CobraCommand().SetArgs([]string{"--unknown"})
+		code, err := cmd.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.Equal(t, []string{"on-error", "on-exit"}, records)
+	})
+}
@@ -3,6 +3,7 @@ package jcli_test
 import (
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/shipengqi/jcli"
 )
@@ -18,6 +19,7 @@ var (
 const levelTmpl = "[%s] "
 
 type testLogger struct {
+	mu sync.Mutex
 	wr io.Writer
 }
 
@@ -66,6 +68,8 @@ func (l *testLogger) Fatal(msg string, keysAndValues ...interface{}) {
 }
 
 func (l *testLogger) write(level []byte, template string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	_, _ = fmt.Fprintf(l.wr, levelTmpl, level)
 	_, _ = fmt.Fprintf(l.wr, template, args...)
 	_, _ = fmt.Fprint(l.wr, "\n")
@@ -0,0 +1,51 @@
+package jcli
+
+import (
+	"context"
+)
+
+// Middleware wraps the run callback of a Command, e.g. for timing, auth checks,
+// logging or recovering. A middleware calls next to continue the execution.
+type Middleware func(next RunCommandContextFunc) RunCommandContextFunc
+
+// Use adds the middlewares to the App. They wrap the run callbacks of all the
+// sub commands added through AddCommands, and are invoked before the
+// middlewares of the commands.
+func (a *App) Use(mw ...Middleware) {
+	a.middlewares = append(a.middlewares, mw...)
+}
+
+// middlewareChain returns the middlewares from the root App down to the
+// current command, the first one is the outermost.
+func (c *Command) middlewareChain() []Middleware {
+	var chain []Middleware
+	current := c
+	for {
+		chain = append(append([]Middleware{}, current.middlewares...), chain...)
+		if current.parent == nil {
+			break
+		}
+		current = current.parent
+	}
+	if current.app != nil {
+		chain = append(append([]Middleware{}, current.app.middlewares...), chain...)
+	}
+	return chain
+}
+
+// wrap applies the middlewares to the run callback of the Command.
+func (c *Command) wrap(run RunCommandContextFunc) RunCommandContextFunc {
+	chain := c.middlewareChain()
+	for i := len(chain) - 1; i >= 0; i-- {
+		run = chain[i](run)
+	}
+	return run
+}
+
+// runWithMiddlewares runs the run callback wrapped by the middlewares.
+func (c *Command) runWithMiddlewares(ctx context.Context, args []string) error {
+	run := c.wrap(func(ctx context.Context, _ *Command, args []string) error {
+		return c.runE(ctx, args)
+	})
+	return run(ctx, c, args)
+}
@@ -0,0 +1,88 @@
+package jcli_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func recordMiddleware(records *[]string, name string) jcli.Middleware {
+	return func(next jcli.RunCommandContextFunc) jcli.RunCommandContextFunc {
+		return func(ctx context.Context, cmd *jcli.Command, args []string) error {
+			*records = append(*records, name+" before "+cmd.Name())
+			err := next(ctx, cmd, args)
+			*records = append(*records, name+" after "+cmd.Name())
+			return err
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("should wrap the run callback of sub commands", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub1", "sub2", "arg"}
+		var records []string
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+		)
+		app.Use(recordMiddleware(&records, "app1"), recordMiddleware(&records, "app2"))
+		sub1 := jcli.NewCommand("sub1", "sub1 command",
+			jcli.WithCommandMiddleware(recordMiddleware(&records, "sub1")),
+		)
+		sub2 := jcli.NewCommand("sub2", "sub2 command",
+			jcli.WithCommandMiddleware(recordMiddleware(&records, "sub2")),
+			jcli.WithCommandPreRun(recordHook(&records, "pre-run")),
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				records = append(records, "run "+args[0])
+				return nil
+			}),
+		)
+		sub1.AddCommands(sub2)
+		app.AddCommands(sub1)
+
+		code, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.Equal(t, []string{
+			"pre-run",
+			"app1 before sub2", "app2 before sub2", "sub1 before sub2", "sub2 before sub2",
+			"run arg",
+			"sub2 after sub2", "sub1 after sub2", "app2 after sub2", "app1 after sub2",
+		}, records)
+	})
+
+	t.Run("should short-circuit the execution", func(t *testing.T) {
+		os.Args = []string{"testApp", "sub1"}
+		denied := errors.New("permission denied")
+		var ran bool
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+		)
+		app.Use(func(next jcli.RunCommandContextFunc) jcli.RunCommandContextFunc {
+			return func(ctx context.Context, cmd *jcli.Command, args []string) error {
+				return denied
+			}
+		})
+		app.AddCommands(jcli.NewCommand("sub1", "sub1 command",
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				ran = true
+				return nil
+			}),
+		))
+
+		code, err := app.Execute(context.Background())
+		assert.ErrorIs(t, err, denied)
+		assert.Equal(t, jcli.ExitCodeError, code)
+		assert.False(t, ran)
+	})
+}
@@ -2,8 +2,10 @@ package jcli
 
 import (
 	"os"
+	"time"
 
 	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/spf13/viper"
 )
 
 // CliOptions abstracts configuration options for reading parameters from the
@@ -53,6 +55,14 @@ func WithRunFunc(run RunFunc) Option {
 	})
 }
 
+// WithRunContextFunc is used to set the application context-aware run callback
+// function option. It takes precedence over WithRunFunc.
+func WithRunContextFunc(run RunContextFunc) Option {
+	return optionFunc(func(a *App) {
+		a.runContextFunc = run
+	})
+}
+
 // WithBaseName is used to set the basename of the cli.
 func WithBaseName(basename string) Option {
 	return optionFunc(func(a *App) {
@@ -96,6 +106,17 @@ func WithCliOptions(opts CliOptions) Option {
 	})
 }
 
+// EnablePersistentCliOptions registers the flags of the CliOptions as
+// persistent flags, which are inherited by all the sub commands. The options
+// are unmarshalled from the configuration, completed and validated before any
+// sub command runs, and are exposed to the sub commands by
+// Command.RootOptions.
+func EnablePersistentCliOptions() Option {
+	return optionFunc(func(a *App) {
+		a.persistentOpts = true
+	})
+}
+
 // EnableSilence sets the application to silent mode, in which the program startup
 // information, flags, configuration information, and version information are not
 // printed in the console.
@@ -117,8 +138,20 @@ func WithAliases(aliases ...string) Option {
 // Register other signals via the signal parameter.
 func WithOnSignalReceived(receiver func(os.Signal), signals ...os.Signal) Option {
 	return optionFunc(func(a *App) {
-		a.signalReceiver = receiver
-		a.signals = signals
+		if len(signals) == 0 {
+			signals = defaultShutdownSignals
+		}
+		for _, sig := range signals {
+			a.signals.register(sig, receiver)
+		}
+	})
+}
+
+// WithSignalHandler registers a handler for the given signal, e.g. SIGHUP to
+// reload, SIGUSR1 to dump state.
+func WithSignalHandler(sig os.Signal, handler SignalHandler) Option {
+	return optionFunc(func(a *App) {
+		a.signals.register(sig, handler)
 	})
 }
 
@@ -129,6 +162,91 @@ func DisableVersion() Option {
 	})
 }
 
+// WithConfigFlag sets the name and the shorthand of the config flag, e.g.
+// WithConfigFlag("kubeconfig", "k"), defaults to "config" and "c".
+func WithConfigFlag(name, shorthand string) Option {
+	return optionFunc(func(a *App) {
+		a.configFlag.name = name
+		a.configFlag.shorthand = shorthand
+	})
+}
+
+// WithConfigFlagUsage sets the usage of the config flag.
+func WithConfigFlagUsage(usage string) Option {
+	return optionFunc(func(a *App) {
+		a.configFlag.usage = usage
+	})
+}
+
+// DisableConfigFlag removes the config flag. Unlike DisableConfig, the
+// configuration file is still searched in the config paths, and bound to the
+// options.
+func DisableConfigFlag() Option {
+	return optionFunc(func(a *App) {
+		a.configFlag.disabled = true
+	})
+}
+
+// EnableLayeredConfig merges the configuration files instead of reading the
+// first one found. The layers are merged in the following order, the later
+// ones take precedence: /etc/<prefix>, ~/.<prefix>, the working directory, the
+// file specified by the config flag, the environment variables and the flags.
+// See WithConfigSearchPaths for the full list of the search paths.
+// The maps are merged recursively, the other values including the lists are
+// replaced.
+func EnableLayeredConfig() Option {
+	return optionFunc(func(a *App) {
+		a.layeredConfig = true
+	})
+}
+
+// EnableStrictConfig fails the App if the configuration files contain the
+// keys which are unknown to the CliOptions, e.g. a typo "usrname". The error
+// aggregates all the unknown keys, with their files, lines and the closest
+// known keys.
+func EnableStrictConfig() Option {
+	return optionFunc(func(a *App) {
+		a.strictConfig = true
+	})
+}
+
+// WithConfigSearchPaths sets the directories to search the configuration file
+// in, the first one has the highest precedence. It defaults to the working
+// directory, $XDG_CONFIG_HOME/<prefix>, ~/.<prefix>, $XDG_CONFIG_DIRS/<prefix>
+// and /etc/<prefix>.
+func WithConfigSearchPaths(paths ...string) Option {
+	return optionFunc(func(a *App) {
+		a.configPaths = append(a.configPaths, paths...)
+	})
+}
+
+// EnableConfigWatch reloads the configuration when the configuration files
+// change or SIGHUP arrives while the App is running. The configuration is
+// unmarshalled into a copy of the options, which is completed and validated.
+// Only the valid copy replaces the options, and is passed to onChange, the
+// invalid configuration is logged and ignored.
+func EnableConfigWatch(onChange ConfigChangeFunc) Option {
+	return optionFunc(func(a *App) {
+		a.configWatcher = &configWatcher{onChange: onChange}
+	})
+}
+
+// EnableConfigCommand adds the built-in "config" command, which provides the
+// "path", "view", "get", "set", "unset", "init" and "validate" sub commands.
+func EnableConfigCommand() Option {
+	return optionFunc(func(a *App) {
+		a.enableConfigCmd = true
+	})
+}
+
+// WithViper sets the viper instance of the App, defaults to a new instance
+// owned by the App.
+func WithViper(v *viper.Viper) Option {
+	return optionFunc(func(a *App) {
+		a.viper = v
+	})
+}
+
 // DisableConfig disable the config flag.
 func DisableConfig() Option {
 	return optionFunc(func(a *App) {
@@ -144,6 +262,142 @@ func EnableCompletion(hidden bool) Option {
 	})
 }
 
+// WithPreRun adds a hook which is invoked after the options are applied and
+// before the run callback of the application.
+func WithPreRun(fn HookFunc) Option {
+	return optionFunc(func(a *App) {
+		a.hooks.preRuns = append(a.hooks.preRuns, fn)
+	})
+}
+
+// WithPostRun adds a hook which is invoked after the run callback of the
+// application succeeds.
+func WithPostRun(fn HookFunc) Option {
+	return optionFunc(func(a *App) {
+		a.hooks.postRuns = append(a.hooks.postRuns, fn)
+	})
+}
+
+// WithPersistentPreRun adds a pre-run hook which is inherited by the sub
+// commands. The persistent pre-run hooks are invoked from the root down to the
+// executing command, before its own pre-run hooks.
+func WithPersistentPreRun(fn HookFunc) Option {
+	return optionFunc(func(a *App) {
+		a.hooks.persistentPreRuns = append(a.hooks.persistentPreRuns, fn)
+	})
+}
+
+// WithPersistentPostRun adds a post-run hook which is inherited by the sub
+// commands. The persistent post-run hooks are invoked from the executing
+// command up to the root, after its own post-run hooks.
+func WithPersistentPostRun(fn HookFunc) Option {
+	return optionFunc(func(a *App) {
+		a.hooks.persistentPostRuns = append(a.hooks.persistentPostRuns, fn)
+	})
+}
+
+// WithOnError adds a hook which is invoked when the application fails.
+func WithOnError(fn ErrorHookFunc) Option {
+	return optionFunc(func(a *App) {
+		a.hooks.onErrors = append(a.hooks.onErrors, fn)
+	})
+}
+
+// WithOnExit adds a hook which is always invoked when the application finishes.
+func WithOnExit(fn ExitHookFunc) Option {
+	return optionFunc(func(a *App) {
+		a.hooks.onExits = append(a.hooks.onExits, fn)
+	})
+}
+
+// WithSignalSource sets the source of the signals, defaults to the os signals.
+// It is useful to inject signals in tests, see SignalInjector. The sub
+// commands inherit the source unless they set their own.
+func WithSignalSource(source SignalSource) Option {
+	return optionFunc(func(a *App) {
+		a.signals.source = source
+	})
+}
+
+// WithShutdownTimeout sets the global timeout of the shutdown callbacks
+// registered by App.OnShutdown, defaults to 30s.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return optionFunc(func(a *App) {
+		a.shutdown.timeout = timeout
+	})
+}
+
+// EnableRecover enables the panic recovery of the application. A panic inside
+// the run callbacks, the lifecycle hooks or the services is converted into an
+// error with ExitCodePanic, and a crash report is written to the crash report
+// directory. The sub commands added through AddCommands inherit the recovery.
+func EnableRecover() Option {
+	return optionFunc(func(a *App) {
+		a.recoverer.enabled = true
+	})
+}
+
+// WithCrashReportDir sets the directory of the crash reports, defaults to the
+// temp directory.
+func WithCrashReportDir(dir string) Option {
+	return optionFunc(func(a *App) {
+		a.recoverer.dir = dir
+	})
+}
+
+// EnablePlugins enables the external executable plugins. When an unknown sub
+// command "foo" is invoked, and an executable named "<prefix>-foo" exists in
+// the plugin directories or on PATH, the executable is run with the remaining
+// arguments. The prefix defaults to the basename of the application.
+// A built-in "plugin list" command is added to list the discovered plugins.
+func EnablePlugins(prefix string) Option {
+	return optionFunc(func(a *App) {
+		a.plugins.enabled = true
+		a.plugins.prefix = prefix
+	})
+}
+
+// WithPluginDirs sets the plugin directories which are searched before PATH.
+func WithPluginDirs(dirs ...string) Option {
+	return optionFunc(func(a *App) {
+		a.plugins.dirs = append(a.plugins.dirs, dirs...)
+	})
+}
+
+// EnablePluginExtensions enables the extension protocol of the plugins of the
+// given names, e.g. "foo" for the executable "demo-foo". The plugins are
+// queried for their command trees, which are added to the App, so the help,
+// the flag parsing and the shell completion cover the plugin commands. The
+// plugins are only queried when the arguments require the plugin commands,
+// the other plugins are run as opaque executables. It must be used with
+// EnablePlugins.
+func EnablePluginExtensions(names ...string) Option {
+	return optionFunc(func(a *App) {
+		if a.plugins.extensions == nil {
+			a.plugins.extensions = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			a.plugins.extensions[name] = true
+		}
+	})
+}
+
+// WithExitCode maps the target error to the exit code, errors returned by the
+// application are matched with errors.Is.
+func WithExitCode(target error, code int) Option {
+	return optionFunc(func(a *App) {
+		a.exitCodes.register(target, code)
+	})
+}
+
+// WithExitCodeMapper adds a mapper to resolve the exit code of the errors
+// returned by the application, it can be used to match errors with errors.As.
+func WithExitCodeMapper(mapper ExitCodeMapper) Option {
+	return optionFunc(func(a *App) {
+		a.exitCodes.registerMapper(mapper)
+	})
+}
+
 // ====================================
 // Command Options
 
@@ -169,6 +423,14 @@ func WithCommandRunFunc(run RunCommandFunc) CommandOption {
 	})
 }
 
+// WithCommandRunContextFunc is used to set the command context-aware startup
+// callback function option. It takes precedence over WithCommandRunFunc.
+func WithCommandRunContextFunc(run RunCommandContextFunc) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.runContextFunc = run
+	})
+}
+
 // WithCommandAliases sets the Command aliases.
 func WithCommandAliases(aliases ...string) CommandOption {
 	return cmdOptionFunc(func(c *Command) {
@@ -206,3 +468,126 @@ func EnableCommandCompletion(hidden bool) CommandOption {
 		c.hideCompletion = hidden
 	})
 }
+
+// WithCommandPreRun adds a hook which is invoked after the options are
+// applied and before the run callback of the Command.
+func WithCommandPreRun(fn HookFunc) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.hooks.preRuns = append(c.hooks.preRuns, fn)
+	})
+}
+
+// WithCommandPostRun adds a hook which is invoked after the run callback of
+// the Command succeeds.
+func WithCommandPostRun(fn HookFunc) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.hooks.postRuns = append(c.hooks.postRuns, fn)
+	})
+}
+
+// WithCommandPersistentPreRun adds a pre-run hook which is inherited by the
+// sub commands of the Command.
+func WithCommandPersistentPreRun(fn HookFunc) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.hooks.persistentPreRuns = append(c.hooks.persistentPreRuns, fn)
+	})
+}
+
+// WithCommandPersistentPostRun adds a post-run hook which is inherited by the
+// sub commands of the Command.
+func WithCommandPersistentPostRun(fn HookFunc) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.hooks.persistentPostRuns = append(c.hooks.persistentPostRuns, fn)
+	})
+}
+
+// WithCommandOnError adds a hook which is invoked when the Command fails.
+func WithCommandOnError(fn ErrorHookFunc) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.hooks.onErrors = append(c.hooks.onErrors, fn)
+	})
+}
+
+// WithCommandOnExit adds a hook which is always invoked when the Command
+// finishes. If the Command fails before its hooks, e.g. on an unknown flag,
+// the on-exit hooks of the App or the root Command are invoked instead.
+func WithCommandOnExit(fn ExitHookFunc) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.hooks.onExits = append(c.hooks.onExits, fn)
+	})
+}
+
+// WithCommandMiddleware adds the middlewares which wrap the run callback of
+// the Command and its sub commands.
+func WithCommandMiddleware(mw ...Middleware) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.middlewares = append(c.middlewares, mw...)
+	})
+}
+
+// WithCommandOnSignalReceived sets a signals' receiver of the Command.
+// SIGTERM and SIGINT are registered by default.
+// Register other signals via the signal parameter.
+func WithCommandOnSignalReceived(receiver func(os.Signal), signals ...os.Signal) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		if len(signals) == 0 {
+			signals = defaultShutdownSignals
+		}
+		for _, sig := range signals {
+			c.signals.register(sig, receiver)
+		}
+	})
+}
+
+// WithCommandSignalHandler registers a handler for the given signal, the
+// handler is active while the Command is running.
+func WithCommandSignalHandler(sig os.Signal, handler SignalHandler) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.signals.register(sig, handler)
+	})
+}
+
+// WithCommandSignalSource sets the source of the signals of the Command and
+// its sub commands, defaults to the source of the parent command or the App,
+// or the os signals.
+func WithCommandSignalSource(source SignalSource) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.signals.source = source
+	})
+}
+
+// EnableCommandRecover enables the panic recovery of the Command and its sub
+// commands. A panic inside the run callback or the lifecycle hooks is
+// converted into an error with ExitCodePanic, and a crash report is written
+// to the crash report directory.
+func EnableCommandRecover() CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.recoverer.enabled = true
+	})
+}
+
+// WithCommandCrashReportDir sets the directory of the crash reports of the
+// Command, defaults to the temp directory.
+func WithCommandCrashReportDir(dir string) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.recoverer.dir = dir
+	})
+}
+
+// WithCommandExitCode maps the target error to the exit code, errors returned
+// by the Command are matched with errors.Is.
+// Set only when use the Command as a root command.
+func WithCommandExitCode(target error, code int) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.exitCodes.register(target, code)
+	})
+}
+
+// WithCommandExitCodeMapper adds a mapper to resolve the exit code of the
+// errors returned by the Command.
+// Set only when use the Command as a root command.
+func WithCommandExitCodeMapper(mapper ExitCodeMapper) CommandOption {
+	return cmdOptionFunc(func(c *Command) {
+		c.exitCodes.registerMapper(mapper)
+	})
+}
@@ -0,0 +1,363 @@
+package jcli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/shipengqi/component-base/version"
+	"github.com/shipengqi/errors"
+	"github.com/shipengqi/golib/sysutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	pluginCommandName = "plugin"
+
+	// PluginEnvCaller is the suffix of the environment variable which holds
+	// the path of the parent executable, e.g. DEMO_PLUGIN_CALLER.
+	PluginEnvCaller = "PLUGIN_CALLER"
+	// PluginEnvConfigFile is the suffix of the environment variable which
+	// holds the config file used by the parent, e.g. DEMO_CONFIG_FILE.
+	PluginEnvConfigFile = "CONFIG_FILE"
+	// PluginEnvVersion is the suffix of the environment variable which holds
+	// the version of the parent, e.g. DEMO_VERSION.
+	PluginEnvVersion = "VERSION"
+)
+
+// Plugin is an external executable named "<prefix>-<name>" which is
+// discovered in the plugin directories or on PATH.
+type Plugin struct {
+	// Name is the name of the plugin without the prefix, e.g. "foo" for the
+	// executable "demo-foo".
+	Name string
+	// Path is the path of the executable.
+	Path string
+}
+
+// plugins discovers and runs the external executable plugins.
+type plugins struct {
+	enabled bool
+	// extensions is the names of the plugins which implement the extension
+	// protocol.
+	extensions map[string]bool
+	loaded     bool
+	prefix     string
+	dirs       []string
+}
+
+// searchDirs returns the plugin directories followed by the PATH entries.
+func (p *plugins) searchDirs() []string {
+	dirs := make([]string, 0, len(p.dirs))
+	dirs = append(dirs, p.dirs...)
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// discover returns the plugins in search order, a plugin which has the same
+// name with a previous one is overshadowed.
+func (p *plugins) discover() []Plugin {
+	var found []Plugin
+	seen := make(map[string]bool)
+	for _, dir := range p.searchDirs() {
+		dir = strings.TrimSpace(dir)
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := p.pluginName(entry.Name())
+			if !ok || entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			found = append(found, Plugin{Name: name, Path: path})
+		}
+	}
+	return found
+}
+
+// lookup finds the plugin of the longest leading non-flag arguments, e.g.
+// "demo-foo-bar" then "demo-foo" for the arguments "foo bar --baz". It returns
+// the path of the plugin and the remaining arguments.
+func (p *plugins) lookup(args []string) (string, []string, bool) {
+	var parts []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		parts = append(parts, arg)
+	}
+
+	for i := len(parts); i > 0; i-- {
+		name := p.prefix + "-" + strings.Join(parts[:i], "-")
+		for _, dir := range p.dirs {
+			if path := filepath.Join(dir, name); isExecutable(path) {
+				return path, args[i:], true
+			}
+		}
+		if path, err := exec.LookPath(name); err == nil {
+			return path, args[i:], true
+		}
+	}
+	return "", nil, false
+}
+
+// pluginName returns the name of the plugin without the prefix and the
+// executable extension.
+func (p *plugins) pluginName(filename string) (string, bool) {
+	if !strings.HasPrefix(filename, p.prefix+"-") {
+		return "", false
+	}
+	name := strings.TrimPrefix(filename, p.prefix+"-")
+	if sysutil.IsWindows() {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name, name != ""
+}
+
+// isTerminal reports whether the file is a terminal.
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if sysutil.IsWindows() {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe", ".bat", ".cmd", ".com":
+			return true
+		}
+		return false
+	}
+	return info.Mode()&0o111 != 0
+}
+
+// Plugins returns the plugins discovered in the plugin directories and on
+// PATH, it returns nil if the plugins are not enabled.
+func (a *App) Plugins() []Plugin {
+	if !a.plugins.enabled {
+		return nil
+	}
+	return a.plugins.discover()
+}
+
+// dispatchPlugin runs the plugin if the arguments do not match any built-in
+// command, and a matched plugin is found. The flags of the root command are
+// allowed before the name of the plugin, e.g. "demo --config x foo".
+func (a *App) dispatchPlugin(ctx context.Context, args []string) (handled bool, code int, err error) {
+	pos, values, ok := a.scanRootFlags(args)
+	if !ok {
+		return false, 0, nil
+	}
+	args = args[pos:]
+	if cmd, _, ferr := a.cmd.Find(args); ferr == nil && cmd != a.cmd {
+		return false, 0, nil
+	}
+	path, pluginArgs, ok := a.plugins.lookup(args)
+	if !ok {
+		return false, 0, nil
+	}
+	// the flags are not parsed when the plugin is dispatched, resolve the
+	// config file from the scanned value
+	if value, ok := values[a.configFlag.name]; ok {
+		a.configFlag.filename = value
+	}
+	code, err = a.execPlugin(ctx, path, pluginArgs)
+	return true, code, err
+}
+
+// scanRootFlags skips the leading flags of the root command, it returns the
+// index of the first positional argument and the values of the skipped flags.
+// It returns false if there is no positional argument, or an unknown flag is
+// found before it.
+func (a *App) scanRootFlags(args []string) (int, map[string]string, bool) {
+	values := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			// the remaining arguments are passed to the root command
+			return 0, nil, false
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			flag := a.rootFlag(name, "")
+			if flag == nil {
+				return 0, nil, false
+			}
+			if !hasValue {
+				value = flag.NoOptDefVal
+				if value == "" {
+					if i++; i == len(args) {
+						return 0, nil, false
+					}
+					value = args[i]
+				}
+			}
+			values[flag.Name] = value
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// the shorthands can be combined, e.g. "-vc x" or "-vcx"
+			shorthands := arg[1:]
+			for j := 0; j < len(shorthands); j++ {
+				flag := a.rootFlag("", shorthands[j:j+1])
+				if flag == nil {
+					return 0, nil, false
+				}
+				value := strings.TrimPrefix(shorthands[j+1:], "=")
+				if flag.NoOptDefVal != "" && !strings.HasPrefix(shorthands[j+1:], "=") {
+					values[flag.Name] = flag.NoOptDefVal
+					continue
+				}
+				if value == "" {
+					if i++; i == len(args) {
+						return 0, nil, false
+					}
+					value = args[i]
+				}
+				values[flag.Name] = value
+				break
+			}
+		default:
+			return i, values, true
+		}
+	}
+	return 0, nil, false
+}
+
+// rootFlag returns the local or persistent flag of the root command by the
+// name or the shorthand.
+func (a *App) rootFlag(name, shorthand string) *pflag.Flag {
+	for _, fs := range []*pflag.FlagSet{a.cmd.Flags(), a.cmd.PersistentFlags()} {
+		if name != "" {
+			if flag := fs.Lookup(name); flag != nil {
+				return flag
+			}
+			continue
+		}
+		if flag := fs.ShorthandLookup(shorthand); flag != nil {
+			return flag
+		}
+	}
+	return nil
+}
+
+// execPlugin runs the plugin with the standard streams of the App, and
+// forwards the exit code of the plugin.
+func (a *App) execPlugin(ctx context.Context, path string, args []string) (int, error) {
+	cmd := exec.CommandContext(ctx, path, args...) // #nosec G204
+	cmd.Stdin = a.cmd.InOrStdin()
+	cmd.Stdout = a.cmd.OutOrStdout()
+	cmd.Stderr = a.cmd.ErrOrStderr()
+	cmd.Env = append(os.Environ(), a.pluginEnv()...)
+
+	if err := cmd.Start(); err != nil {
+		return ExitCodeError, err
+	}
+
+	// the plugin handles the shutdown signals itself, forward them instead
+	// of terminating the App before the plugin exits. The interrupt from a
+	// terminal is sent to the whole foreground process group, the plugin has
+	// received it already.
+	interactive := isTerminal(os.Stdin)
+	unregisters := make([]func(), 0, len(defaultShutdownSignals))
+	for _, sig := range defaultShutdownSignals {
+		forward := sig != os.Interrupt || !interactive
+		unregisters = append(unregisters, a.signals.register(sig, func(sig os.Signal) {
+			if forward {
+				_ = cmd.Process.Signal(sig)
+			}
+		}))
+	}
+	defer func() {
+		for _, unregister := range unregisters {
+			unregister()
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+			// the plugin has reported its error
+			return exitErr.ExitCode(), NewExitError(exitErr.ExitCode(), nil)
+		}
+		return ExitCodeError, fmt.Errorf("run plugin %s: %w", path, err)
+	}
+	return ExitCodeOK, nil
+}
+
+// pluginEnv returns the environment variables which describe the App.
+func (a *App) pluginEnv() []string {
+	prefix := envPrefix(NormalizeCliName(a.basename))
+	env := []string{
+		fmt.Sprintf("%s_%s=%s", prefix, PluginEnvVersion, version.Get().Version),
+	}
+	if caller, err := os.Executable(); err == nil {
+		env = append(env, fmt.Sprintf("%s_%s=%s", prefix, PluginEnvCaller, caller))
+	}
+	if !a.disableConfig && a.readConfig(a.basename) == nil {
+		// the file of the highest precedence
+		if files := a.configFilesUsed(); len(files) > 0 {
+			env = append(env, fmt.Sprintf("%s_%s=%s", prefix, PluginEnvConfigFile, files[len(files)-1]))
+		}
+	}
+	return env
+}
+
+func (a *App) pluginCommand() *Command {
+	list := NewCommand("list", "List all visible plugin executables.",
+		WithCommandDesc(fmt.Sprintf("List all available plugin files on the plugin directories and PATH.\n\n"+
+			"Available plugin files are those that are executable and begin with %q.", a.plugins.prefix+"-")),
+		WithCommandRunFunc(func(cmd *Command, args []string) error {
+			return a.listPlugins(cmd)
+		}),
+	)
+	c := NewCommand(pluginCommandName, "Provides utilities for interacting with plugins.",
+		WithCommandRunFunc(func(cmd *Command, args []string) error {
+			return cmd.Help()
+		}),
+	)
+	c.builtin = true
+	c.AddCommands(list)
+	return c
+}
+
+func (a *App) listPlugins(cmd *Command) error {
+	found := a.plugins.discover()
+	if len(found) == 0 {
+		return fmt.Errorf("unable to find any %s plugins in the plugin directories and PATH", a.plugins.prefix)
+	}
+
+	out := cmd.CobraCommand().OutOrStdout()
+	_, _ = fmt.Fprintln(out, "The following compatible plugins are available:")
+	_, _ = fmt.Fprintln(out)
+
+	active := make(map[string]string)
+	for _, p := range found {
+		_, _ = fmt.Fprintln(out, p.Path)
+		if path, ok := active[p.Name]; ok {
+			_, _ = fmt.Fprintf(out, "  - %s %s is overshadowed by a similarly named plugin: %s\n",
+				Yellow("warning:"), p.Path, path)
+			continue
+		}
+		active[p.Name] = p.Path
+		if c, _, err := a.cmd.Find(strings.Split(p.Name, "-")); err == nil && c != a.cmd {
+			_, _ = fmt.Fprintf(out, "  - %s %s is shadowed by the built-in command %q\n",
+				Yellow("warning:"), p.Path, c.CommandPath())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/shipengqi/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) string {
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755) // #nosec G306
+	assert.NoError(t, err)
+	return path
+}
+
+func newPluginApp(dir string, opts ...jcli.Option) *jcli.App {
+	opts = append([]jcli.Option{
+		jcli.WithBaseName("testApp"),
+		jcli.WithLogger(newTestLogger(&bytes.Buffer{})),
+		jcli.EnableSilence(),
+		jcli.DisableConfig(),
+		jcli.DisableVersion(),
+		jcli.EnablePlugins(""),
+		jcli.WithPluginDirs(dir),
+	}, opts...)
+	return jcli.New("simple", opts...)
+}
+
+func TestAppPlugins(t *testing.T) {
+	t.Run("should run the plugin and forward the exit code", func(t *testing.T) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "out")
+		writePlugin(t, dir, "testApp-foo",
+			`echo "args: $@" > `+out+`
+echo "caller: $TESTAPP_PLUGIN_CALLER" >> `+out+`
+echo "version: $TESTAPP_VERSION" >> `+out+`
+exit 3`)
+
+		os.Args = []string{"testApp", "foo", "bar", "--baz"}
+		app := newPluginApp(dir, jcli.WithRunFunc(func() error {
+			t.Fatal("the application should not run")
+			return nil
+		}))
+		code, err := app.Execute(context.Background())
+		assert.Equal(t, 3, code)
+		var exitErr *jcli.ExitError
+		assert.True(t, errors.As(err, &exitErr))
+		assert.Nil(t, exitErr.Err)
+
+		content, _ := os.ReadFile(out)
+		assert.Contains(t, string(content), "args: bar --baz")
+		assert.Contains(t, string(content), "version: ")
+		assert.NotContains(t, string(content), "caller: \n")
+	})
+
+	t.Run("should run the plugin of the longest match", func(t *testing.T) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "out")
+		writePlugin(t, dir, "testApp-foo", `echo "foo $@" > `+out)
+		writePlugin(t, dir, "testApp-foo-bar", `echo "foo-bar $@" > `+out)
+
+		os.Args = []string{"testApp", "foo", "bar", "baz"}
+		code, err := newPluginApp(dir).Execute(context.Background())
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+
+		content, _ := os.ReadFile(out)
+		assert.Equal(t, "foo-bar baz\n", string(content))
+	})
+
+	t.Run("built-in commands should take precedence over plugins", func(t *testing.T) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "out")
+		writePlugin(t, dir, "testApp-sub1", `echo "plugin" > `+out)
+
+		var buf bytes.Buffer
+		log := newTestLogger(&buf)
+		os.Args = []string{"testApp", "sub1"}
+		app := newPluginApp(dir)
+		app.AddCommands(jcli.NewCommand("sub1", "sub1 command",
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				log.Infof("sub1 running")
+				return nil
+			})))
+		code, err := app.Execute(context.Background())
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "[info] sub1 running")
+		assert.NoFileExists(t, out)
+	})
+
+	t.Run("should list the plugins", func(t *testing.T) {
+		dir := t.TempDir()
+		other := t.TempDir()
+		foo := writePlugin(t, dir, "testApp-foo", "exit 0")
+		sub1 := writePlugin(t, dir, "testApp-sub1", "exit 0")
+		shadowed := writePlugin(t, other, "testApp-foo", "exit 0")
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "testApp-noexec"), nil, 0o600))
+
+		r, w, _ := os.Pipe()
+		tmp := os.Stdout
+		defer func() {
+			os.Stdout = tmp
+		}()
+		os.Stdout = w
+		os.Args = []string{"testApp", "plugin", "list"}
+		app := newPluginApp(dir, jcli.WithPluginDirs(other))
+		app.AddCommands(jcli.NewCommand("sub1", "sub1 command"))
+		code, err := app.Execute(context.Background())
+		_ = w.Close()
+		stdout, _ := io.ReadAll(r)
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+		assert.Contains(t, string(stdout), foo)
+		assert.Contains(t, string(stdout), shadowed+" is overshadowed by a similarly named plugin: "+foo)
+		assert.Contains(t, string(stdout), sub1+" is shadowed by the built-in command \"testApp sub1\"")
+		assert.NotContains(t, string(stdout), "testApp-noexec")
+
+		plugins := app.Plugins()
+		names := make([]string, 0, len(plugins))
+		for _, p := range plugins {
+			names = append(names, p.Name)
+		}
+		assert.Subset(t, names, []string{"foo", "sub1"})
+	})
+
+	t.Run("should run the plugin after the root flags", func(t *testing.T) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "out")
+		config := filepath.Join(dir, "custom.yaml")
+		assert.NoError(t, os.WriteFile(config, []byte("username: admin\n"), 0o600))
+		writePlugin(t, dir, "testApp-foo", `echo "config: $TESTAPP_CONFIG_FILE args: $@" > `+out)
+
+		os.Args = []string{"testApp", "--username=admin", "-c", config, "foo", "--password", "bar"}
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithCliOptions(&fakeCliOptions{}),
+			jcli.WithLogger(newTestLogger(&bytes.Buffer{})),
+			jcli.EnableSilence(),
+			jcli.DisableVersion(),
+			jcli.EnablePlugins(""),
+			jcli.WithPluginDirs(dir),
+		)
+		code, err := app.Execute(context.Background())
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+
+		content, _ := os.ReadFile(out)
+		assert.Equal(t, "config: "+config+" args: --password bar\n", string(content))
+	})
+
+	t.Run("should not run the plugin after an unknown flag", func(t *testing.T) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "out")
+		writePlugin(t, dir, "testApp-foo", `echo "plugin" > `+out)
+
+		os.Args = []string{"testApp", "--unknown", "foo"}
+		code, err := newPluginApp(dir).Execute(context.Background())
+		assert.NotEqual(t, 0, code)
+		assert.Error(t, err)
+		assert.NoFileExists(t, out)
+	})
+
+	t.Run("should forward the termination signal to the plugin", func(t *testing.T) {
+		dir := t.TempDir()
+		ready := filepath.Join(dir, "ready")
+		out := filepath.Join(dir, "out")
+		writePlugin(t, dir, "testApp-foo", `trap 'echo terminated > `+out+`; exit 5' TERM
+touch `+ready+`
+while :; do sleep 0.1; done`)
+
+		os.Args = []string{"testApp", "foo"}
+		injector := jcli.NewSignalInjector()
+		go func() {
+			for {
+				if _, err := os.Stat(ready); err == nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			injector.Send(syscall.SIGTERM)
+		}()
+		code, _ := newPluginApp(dir, jcli.WithSignalSource(injector)).Execute(context.Background())
+		assert.Equal(t, 5, code)
+
+		content, _ := os.ReadFile(out)
+		assert.Equal(t, "terminated\n", string(content))
+	})
+}
+
+const extensionPlugin = `if [ "$1" = "__jcli_rpc" ]; then
+  read -r request
+  echo "$request" >> %[1]s.rpc
+  case "$request" in
+  *describe*)
+    echo '{"jsonrpc":"2.0","id":1,"result":{"name":"ignored","short":"Manage the foo resources.",` +
+	`"flags":[{"name":"verbose","shorthand":"v","type":"bool","usage":"Print more details."}],` +
+	`"commands":[{"name":"get","short":"Get a foo resource.","validArgs":["alpha","beta"],` +
+	`"flags":[{"name":"output","shorthand":"o","default":"text","usage":"Output format.","completions":["text","json"]},` +
+	`{"name":"label","type":"stringSlice","usage":"Filter by labels."}]},` +
+	`{"name":"delete","short":"Delete a foo resource.","dynamicCompletion":true}]}}'
+    ;;
+  *complete*)
+    echo '{"jsonrpc":"2.0","id":1,"result":{"completions":["gamma"],"noFileComp":true}}'
+    ;;
+  esac
+  exit 0
+fi
+echo "args: $@" > %[1]s
+exit 4`
+
+func TestAppPluginExtensions(t *testing.T) {
+	setup := func(t *testing.T) (string, string) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "out")
+		writePlugin(t, dir, "testApp-foo", fmt.Sprintf(extensionPlugin, out))
+		writePlugin(t, dir, "testApp-opaque", `echo "$@" >> `+out+`.opaque`)
+		return dir, out
+	}
+	execute := func(t *testing.T, dir string, args ...string) (int, error, string) {
+		r, w, _ := os.Pipe()
+		tmp := os.Stdout
+		defer func() {
+			os.Stdout = tmp
+		}()
+		os.Stdout = w
+		os.Args = append([]string{"testApp"}, args...)
+		code, err := newPluginApp(dir, jcli.EnablePluginExtensions("foo")).Execute(context.Background())
+		_ = w.Close()
+		stdout, _ := io.ReadAll(r)
+		return code, err, string(stdout)
+	}
+
+	t.Run("help message should contain the plugin commands", func(t *testing.T) {
+		dir, _ := setup(t)
+		code, err, stdout := execute(t, dir, "--help")
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "Manage the foo resources.")
+		assert.NotContains(t, stdout, "opaque")
+
+		code, err, stdout = execute(t, dir, "foo", "get", "--help")
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "Get a foo resource.")
+		assert.Contains(t, stdout, "-o, --output string")
+		assert.Contains(t, stdout, "(default \"text\")")
+	})
+
+	t.Run("should proxy the invocation to the plugin", func(t *testing.T) {
+		dir, out := setup(t)
+		code, err, _ := execute(t, dir, "foo", "get", "-o", "json", "--label", "a", "--label", "b", "alpha")
+		assert.Equal(t, 4, code)
+		var exitErr *jcli.ExitError
+		assert.True(t, errors.As(err, &exitErr))
+
+		content, _ := os.ReadFile(out)
+		assert.Equal(t, "args: get --label=a --label=b --output=json alpha\n", string(content))
+	})
+
+	t.Run("should parse the flags of the plugin commands", func(t *testing.T) {
+		dir, out := setup(t)
+		code, err, _ := execute(t, dir, "foo", "get", "--unknown")
+		assert.Equal(t, 1, code)
+		assert.ErrorContains(t, err, "unknown flag: --unknown")
+		assert.NoFileExists(t, out)
+	})
+
+	t.Run("should complete the plugin commands", func(t *testing.T) {
+		dir, _ := setup(t)
+		_, err, stdout := execute(t, dir, "__complete", "foo", "")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "get\tGet a foo resource.")
+		assert.Contains(t, stdout, "delete\tDelete a foo resource.")
+
+		_, err, stdout = execute(t, dir, "__complete", "foo", "get", "")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "alpha\nbeta\n")
+
+		_, err, stdout = execute(t, dir, "__complete", "foo", "get", "--output", "")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "text\njson\n")
+
+		_, err, stdout = execute(t, dir, "__complete", "foo", "delete", "")
+		assert.NoError(t, err)
+		assert.Contains(t, stdout, "gamma\n:4\n")
+	})
+
+	t.Run("opaque plugins should still be run", func(t *testing.T) {
+		dir, out := setup(t)
+		code, err, _ := execute(t, dir, "opaque", "bar")
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+
+		// the opaque plugin is not queried over the extension protocol
+		content, _ := os.ReadFile(out + ".opaque")
+		assert.Equal(t, "bar\n", string(content))
+	})
+
+	t.Run("should not query the plugins for the built-in commands", func(t *testing.T) {
+		dir, out := setup(t)
+		code, err, _ := execute(t, dir)
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+		assert.NoFileExists(t, out+".rpc")
+
+		code, err, _ = execute(t, dir, "plugin", "list")
+		assert.Equal(t, 0, code)
+		assert.NoError(t, err)
+		assert.NoFileExists(t, out+".rpc")
+
+		_, _, _ = execute(t, dir, "--help")
+		content, _ := os.ReadFile(out + ".rpc")
+		assert.Contains(t, string(content), `"method":"describe"`)
+	})
+}
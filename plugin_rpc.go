@@ -0,0 +1,456 @@
+package jcli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	cliflag "github.com/shipengqi/component-base/cli/flag"
+	"github.com/shipengqi/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// PluginRPCArg is the only argument passed to a plugin when it is queried
+	// over the extension protocol. The plugin reads a JSON-RPC 2.0 request
+	// from stdin and writes the response to stdout, see ServePluginRPC.
+	PluginRPCArg = "__jcli_rpc"
+	// PluginProtocolVersion is the version of the extension protocol.
+	PluginProtocolVersion = 1
+
+	// PluginMethodDescribe is the method to query the command tree of a plugin,
+	// the result is a PluginCommandSpec.
+	PluginMethodDescribe = "describe"
+	// PluginMethodComplete is the method to query the completions of the
+	// arguments, the params is a PluginCompleteParams, the result is a
+	// PluginCompleteResult.
+	PluginMethodComplete = "complete"
+
+	jsonrpcVersion          = "2.0"
+	flagSetNamePlugin       = "plugin"
+	defaultPluginRPCTimeout = 5 * time.Second
+)
+
+// PluginCommandSpec describes a command of a plugin.
+type PluginCommandSpec struct {
+	// Name is the name of the command. The name of the top-level command is
+	// always the name of the plugin.
+	Name     string              `json:"name"`
+	Short    string              `json:"short,omitempty"`
+	Long     string              `json:"long,omitempty"`
+	Example  string              `json:"example,omitempty"`
+	Aliases  []string            `json:"aliases,omitempty"`
+	Hidden   bool                `json:"hidden,omitempty"`
+	Flags    []PluginFlagSpec    `json:"flags,omitempty"`
+	Commands []PluginCommandSpec `json:"commands,omitempty"`
+	// ValidArgs is the static completions of the positional arguments.
+	ValidArgs []string `json:"validArgs,omitempty"`
+	// DynamicCompletion indicates the completions of the positional arguments
+	// are queried with the PluginMethodComplete method.
+	DynamicCompletion bool `json:"dynamicCompletion,omitempty"`
+}
+
+// PluginFlagSpec describes a flag of a plugin command.
+type PluginFlagSpec struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Usage     string `json:"usage,omitempty"`
+	// Type is one of "string", "bool", "int", "float", "duration" and
+	// "stringSlice", defaults to "string".
+	Type     string `json:"type,omitempty"`
+	Default  string `json:"default,omitempty"`
+	Hidden   bool   `json:"hidden,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	// Completions is the static completions of the flag value.
+	Completions []string `json:"completions,omitempty"`
+}
+
+// PluginDescribeParams is the params of the PluginMethodDescribe method.
+type PluginDescribeParams struct {
+	Version int `json:"version"`
+}
+
+// PluginCompleteParams is the params of the PluginMethodComplete method.
+type PluginCompleteParams struct {
+	// Command is the command path below the plugin, e.g. ["foo", "bar"] for
+	// "demo foo bar".
+	Command    []string `json:"command"`
+	Args       []string `json:"args"`
+	ToComplete string   `json:"toComplete"`
+}
+
+// PluginCompleteResult is the result of the PluginMethodComplete method.
+type PluginCompleteResult struct {
+	Completions []string `json:"completions"`
+	// NoFileComp disables the file name completion of the shell.
+	NoFileComp bool `json:"noFileComp,omitempty"`
+}
+
+// PluginCompleteFunc returns the completions of the arguments.
+type PluginCompleteFunc func(params PluginCompleteParams) (PluginCompleteResult, error)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// the error codes defined by JSON-RPC 2.0.
+const (
+	rpcCodeParseError     = -32700
+	rpcCodeMethodNotFound = -32601
+	rpcCodeInternalError  = -32603
+)
+
+// IsPluginRPC reports whether the plugin is queried over the extension
+// protocol.
+func IsPluginRPC(args []string) bool {
+	return len(args) == 2 && args[1] == PluginRPCArg
+}
+
+// ServePluginRPC serves a request of the extension protocol on the plugin
+// side. The complete function can be nil if none of the commands has the
+// DynamicCompletion.
+//
+//	if jcli.IsPluginRPC(os.Args) {
+//		_ = jcli.ServePluginRPC(os.Stdin, os.Stdout, spec, nil)
+//		return
+//	}
+func ServePluginRPC(r io.Reader, w io.Writer, spec PluginCommandSpec, complete PluginCompleteFunc) error {
+	resp := rpcResponse{JSONRPC: jsonrpcVersion}
+
+	var req rpcRequest
+	line, err := bufio.NewReader(r).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if err = json.Unmarshal(line, &req); err != nil {
+		resp.Error = &rpcError{Code: rpcCodeParseError, Message: err.Error()}
+		return writeRPC(w, resp)
+	}
+	resp.ID = req.ID
+
+	var result interface{}
+	switch req.Method {
+	case PluginMethodDescribe:
+		result = spec
+	case PluginMethodComplete:
+		var params PluginCompleteParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &rpcError{Code: rpcCodeParseError, Message: err.Error()}
+			return writeRPC(w, resp)
+		}
+		if complete == nil {
+			result = PluginCompleteResult{}
+			break
+		}
+		if result, err = complete(params); err != nil {
+			resp.Error = &rpcError{Code: rpcCodeInternalError, Message: err.Error()}
+			return writeRPC(w, resp)
+		}
+	default:
+		resp.Error = &rpcError{Code: rpcCodeMethodNotFound, Message: "method not found: " + req.Method}
+		return writeRPC(w, resp)
+	}
+
+	if resp.Result, err = json.Marshal(result); err != nil {
+		return err
+	}
+	return writeRPC(w, resp)
+}
+
+func writeRPC(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// callPlugin runs the plugin with the PluginRPCArg and the environment
+// variables, and sends a request of the extension protocol.
+func callPlugin(ctx context.Context, path string, env []string, method string, params, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultPluginRPCTimeout)
+	defer cancel()
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := json.Marshal(rpcRequest{JSONRPC: jsonrpcVersion, ID: 1, Method: method, Params: raw})
+	if err != nil {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, PluginRPCArg) // #nosec G204
+	cmd.Stdin = bytes.NewReader(append(req, '\n'))
+	cmd.Stdout = &stdout
+	cmd.Env = append(os.Environ(), env...)
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("call plugin %s: %w", path, err)
+	}
+
+	var resp rpcResponse
+	if err = json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fmt.Errorf("call plugin %s: %w", path, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("call plugin %s: %w", path, resp.Error)
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// needPluginExtensions reports whether the command trees of the plugins are
+// required by the arguments: the help and the completion of the App, or an
+// argument which does not match any built-in command.
+func (a *App) needPluginExtensions(args []string) bool {
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			return true
+		}
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+	}
+	pos, _, ok := a.scanRootFlags(args)
+	if !ok {
+		return false
+	}
+	switch args[pos] {
+	case commandHelp, cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	cmd, _, err := a.cmd.Find(args[pos:])
+	return err != nil || cmd == a.cmd
+}
+
+// loadPluginExtensions queries the plugins set by EnablePluginExtensions, and
+// adds their command trees to the App. The other plugins, and the plugins
+// which do not respond to the protocol, are still run as opaque executables.
+// The plugins are queried once per App.
+func (a *App) loadPluginExtensions(ctx context.Context) {
+	if a.plugins.loaded {
+		return
+	}
+	a.plugins.loaded = true
+
+	var candidates []Plugin
+	seen := make(map[string]bool)
+	for _, p := range a.plugins.discover() {
+		if seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		// the built-in commands take precedence over the plugins
+		if c, _, err := a.cmd.Find([]string{p.Name}); err == nil && c != a.cmd {
+			continue
+		}
+		if !a.plugins.extensions[p.Name] {
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+
+	// the environment reads the configuration, resolve it before the plugins
+	// are queried in parallel
+	env := a.pluginEnv()
+	specs := make([]*PluginCommandSpec, len(candidates))
+	var wg sync.WaitGroup
+	for i, p := range candidates {
+		wg.Add(1)
+		go func(i int, p Plugin) {
+			defer wg.Done()
+			spec := &PluginCommandSpec{}
+			err := callPlugin(ctx, p.Path, env, PluginMethodDescribe,
+				PluginDescribeParams{Version: PluginProtocolVersion}, spec)
+			if err == nil {
+				spec.Name = p.Name
+				err = validatePluginSpec(spec, a.cmd.PersistentFlags())
+			}
+			if err != nil {
+				a.logger.Debugf("%s Plugin %s is not an extension: %v", progressMessage, p.Path, err)
+				return
+			}
+			specs[i] = spec
+		}(i, p)
+	}
+	wg.Wait()
+
+	for i, p := range candidates {
+		if specs[i] == nil {
+			continue
+		}
+		a.AddCommands(a.pluginExtensionCommand(p, specs[i], nil))
+	}
+}
+
+// validatePluginSpec checks the flags which cannot be added to the commands,
+// including the flags inherited from the root command.
+func validatePluginSpec(spec *PluginCommandSpec, inherited *pflag.FlagSet) error {
+	if spec.Name == "" {
+		return errors.New("command name is empty")
+	}
+	names := make(map[string]bool)
+	shorthands := make(map[string]bool)
+	for _, f := range spec.Flags {
+		switch {
+		case f.Name == "" || f.Name == flagHelp || inherited.Lookup(f.Name) != nil:
+			return fmt.Errorf("command %s: invalid flag name %q", spec.Name, f.Name)
+		case names[f.Name]:
+			return fmt.Errorf("command %s: duplicate flag %q", spec.Name, f.Name)
+		case len(f.Shorthand) > 1 || f.Shorthand == "h" ||
+			(f.Shorthand != "" && (shorthands[f.Shorthand] || inherited.ShorthandLookup(f.Shorthand) != nil)):
+			return fmt.Errorf("command %s: invalid shorthand %q of flag %q", spec.Name, f.Shorthand, f.Name)
+		}
+		names[f.Name] = true
+		shorthands[f.Shorthand] = true
+	}
+	for i := range spec.Commands {
+		if err := validatePluginSpec(&spec.Commands[i], inherited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pluginExtensionCommand materializes the command spec, the invocation of the
+// command is proxied to the plugin.
+func (a *App) pluginExtensionCommand(p Plugin, spec *PluginCommandSpec, path []string) *Command {
+	c := NewCommand(spec.Name, spec.Short,
+		WithCommandDesc(spec.Long),
+		WithCommandExamples(spec.Example),
+		WithCommandAliases(spec.Aliases...),
+		WithCommandCliOptions(&pluginOptions{flags: spec.Flags}),
+		WithCommandRunFunc(func(cmd *Command, args []string) error {
+			_, err := a.execPlugin(cmd.CobraCommand().Context(), p.Path, pluginArgs(cmd.CobraCommand(), path, args))
+			return err
+		}),
+	)
+
+	cmd := c.CobraCommand()
+	cmd.Hidden = spec.Hidden
+	for _, f := range spec.Flags {
+		if f.Required {
+			_ = cmd.MarkFlagRequired(f.Name)
+		}
+		if len(f.Completions) > 0 {
+			completions := f.Completions
+			_ = cmd.RegisterFlagCompletionFunc(f.Name,
+				func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+					return completions, cobra.ShellCompDirectiveNoFileComp
+				})
+		}
+	}
+	switch {
+	case spec.DynamicCompletion:
+		cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			var result PluginCompleteResult
+			params := PluginCompleteParams{Command: path, Args: args, ToComplete: toComplete}
+			if err := callPlugin(cmd.Context(), p.Path, a.pluginEnv(), PluginMethodComplete, params, &result); err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			if result.NoFileComp {
+				return result.Completions, cobra.ShellCompDirectiveNoFileComp
+			}
+			return result.Completions, cobra.ShellCompDirectiveDefault
+		}
+	case len(spec.ValidArgs) > 0:
+		cmd.ValidArgs = spec.ValidArgs
+	}
+
+	for i := range spec.Commands {
+		sub := &spec.Commands[i]
+		subpath := append(append([]string{}, path...), sub.Name)
+		c.AddCommands(a.pluginExtensionCommand(p, sub, subpath))
+	}
+	return c
+}
+
+// pluginArgs returns the arguments passed to the plugin: the command path
+// below the plugin, the changed flags and the positional arguments.
+func pluginArgs(cmd *cobra.Command, path, args []string) []string {
+	pargs := append([]string{}, path...)
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if !f.Changed || f.Name == flagHelp {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range sv.GetSlice() {
+				pargs = append(pargs, fmt.Sprintf("--%s=%s", f.Name, v))
+			}
+			return
+		}
+		pargs = append(pargs, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			pargs = append(pargs, "--")
+			break
+		}
+	}
+	return append(pargs, args...)
+}
+
+// pluginOptions is the CliOptions of a materialized plugin command, the flag
+// values are only held by the flags which are forwarded to the plugin.
+type pluginOptions struct {
+	flags []PluginFlagSpec
+}
+
+func (o *pluginOptions) Flags() (fss cliflag.NamedFlagSets) {
+	fs := fss.FlagSet(flagSetNamePlugin)
+	for _, f := range o.flags {
+		switch f.Type {
+		case "bool":
+			fs.BoolP(f.Name, f.Shorthand, false, f.Usage)
+		case "int":
+			fs.IntP(f.Name, f.Shorthand, 0, f.Usage)
+		case "float":
+			fs.Float64P(f.Name, f.Shorthand, 0, f.Usage)
+		case "duration":
+			fs.DurationP(f.Name, f.Shorthand, 0, f.Usage)
+		case "stringSlice":
+			fs.StringSliceP(f.Name, f.Shorthand, nil, f.Usage)
+		default:
+			fs.StringP(f.Name, f.Shorthand, "", f.Usage)
+		}
+		flag := fs.Lookup(f.Name)
+		if f.Default != "" && flag.Value.Set(f.Default) == nil {
+			flag.DefValue = flag.Value.String()
+		}
+		flag.Hidden = f.Hidden
+	}
+	return fss
+}
+
+func (o *pluginOptions) Validate() []error {
+	return nil
+}
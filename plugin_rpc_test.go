@@ -0,0 +1,77 @@
+package jcli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/shipengqi/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func TestIsPluginRPC(t *testing.T) {
+	assert.True(t, jcli.IsPluginRPC([]string{"demo-foo", jcli.PluginRPCArg}))
+	assert.False(t, jcli.IsPluginRPC([]string{"demo-foo"}))
+	assert.False(t, jcli.IsPluginRPC([]string{"demo-foo", jcli.PluginRPCArg, "bar"}))
+}
+
+func TestServePluginRPC(t *testing.T) {
+	spec := jcli.PluginCommandSpec{
+		Name:  "foo",
+		Short: "foo plugin",
+		Flags: []jcli.PluginFlagSpec{{Name: "name", Type: "string"}},
+	}
+	complete := func(params jcli.PluginCompleteParams) (jcli.PluginCompleteResult, error) {
+		if params.ToComplete == "" {
+			return jcli.PluginCompleteResult{}, errors.New("nothing to complete")
+		}
+		return jcli.PluginCompleteResult{Completions: []string{params.ToComplete + "1"}}, nil
+	}
+
+	tests := []struct {
+		title    string
+		request  string
+		expected string
+	}{
+		{
+			"describe",
+			`{"jsonrpc":"2.0","id":7,"method":"describe","params":{"version":1}}`,
+			`{"jsonrpc":"2.0","id":7,"result":{"name":"foo","short":"foo plugin","flags":[{"name":"name","type":"string"}]}}`,
+		},
+		{
+			"complete",
+			`{"jsonrpc":"2.0","id":1,"method":"complete","params":{"command":[],"args":[],"toComplete":"b"}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"completions":["b1"]}}`,
+		},
+		{
+			"complete error",
+			`{"jsonrpc":"2.0","id":1,"method":"complete","params":{"command":[],"args":[],"toComplete":""}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"nothing to complete"}}`,
+		},
+		{
+			"unknown method",
+			`{"jsonrpc":"2.0","id":1,"method":"unknown"}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found: unknown"}}`,
+		},
+	}
+	for _, v := range tests {
+		t.Run(v.title, func(t *testing.T) {
+			var out bytes.Buffer
+			err := jcli.ServePluginRPC(strings.NewReader(v.request+"\n"), &out, spec, complete)
+			assert.NoError(t, err)
+			assert.JSONEq(t, v.expected, out.String())
+		})
+	}
+
+	t.Run("invalid request", func(t *testing.T) {
+		var out bytes.Buffer
+		err := jcli.ServePluginRPC(strings.NewReader("invalid\n"), &out, spec, nil)
+		assert.NoError(t, err)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &resp))
+		assert.Contains(t, resp, "error")
+	})
+}
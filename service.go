@@ -0,0 +1,64 @@
+package jcli
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/shipengqi/errors"
+)
+
+// ServiceFunc defines the start function of a background service. It should
+// block until the service stops, and return when the context is canceled.
+type ServiceFunc func(ctx context.Context) error
+
+type service struct {
+	name  string
+	start ServiceFunc
+}
+
+// AddService adds a background service to the App, e.g. an HTTP server, a
+// worker or a watcher. The services are started along with the run callback
+// after the options are applied, and are supervised with errgroup semantics:
+// the first failure cancels the rest, and is returned by the App. The context
+// of the services is canceled when one of the shutdown signals arrives.
+func (a *App) AddService(name string, start ServiceFunc) {
+	a.services = append(a.services, &service{name: name, start: start})
+}
+
+// runServices starts the services and waits for all of them to stop, it
+// returns the first failure.
+func (a *App) runServices(ctx context.Context, services []*service) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, s := range services {
+		wg.Add(1)
+		go func(s *service) {
+			defer wg.Done()
+
+			a.logger.Infof("%s Service %s started", progressMessage, s.name)
+			err := a.recoverer.call(a.cmd, func() error {
+				return s.start(ctx)
+			})
+			// a service stopped by the cancellation is not considered as failed
+			if err == nil || (errors.Is(err, context.Canceled) && ctx.Err() != nil) {
+				a.logger.Infof("%s Service %s stopped", progressMessage, s.name)
+				return
+			}
+			a.logger.Errorf("%s Service %s failed: %v", progressMessage, s.name, err)
+			once.Do(func() {
+				firstErr = fmt.Errorf("service %s: %w", s.name, err)
+				cancel()
+			})
+		}(s)
+	}
+	wg.Wait()
+
+	return firstErr
+}
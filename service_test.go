@@ -0,0 +1,81 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func TestAppServices(t *testing.T) {
+	t.Run("should cancel the rest when a service fails", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		serviceErr := errors.New("listen failed")
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+		)
+		app.AddService("worker", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		app.AddService("server", func(ctx context.Context) error {
+			return serviceErr
+		})
+		code, err := app.Execute(context.Background())
+		assert.ErrorIs(t, err, serviceErr)
+		assert.Equal(t, jcli.ExitCodeError, code)
+		assert.Contains(t, buf.String(), "Service server failed: listen failed")
+		assert.Contains(t, buf.String(), "Service worker stopped")
+	})
+
+	t.Run("should stop services on shutdown signals", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		injector := jcli.NewSignalInjector()
+		running := make(chan struct{})
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithSignalSource(injector),
+			jcli.WithRunContextFunc(func(ctx context.Context) error {
+				close(running)
+				<-ctx.Done()
+				return nil
+			}),
+		)
+		app.AddService("worker", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		done := make(chan struct{})
+		var (
+			code int
+			err  error
+		)
+		go func() {
+			defer close(done)
+			code, err = app.Execute(context.Background())
+		}()
+		<-running
+		assert.True(t, injector.Send(syscall.SIGTERM))
+		<-done
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.Contains(t, buf.String(), "Service simple started")
+		assert.Contains(t, buf.String(), "Service worker stopped")
+	})
+}
@@ -0,0 +1,194 @@
+package jcli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shipengqi/errors"
+)
+
+const (
+	// ExitCodeForcedShutdown is the exit code when the application is forced to
+	// exit by a second shutdown signal or the shutdown timeout expiry.
+	ExitCodeForcedShutdown = 130
+
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// ErrShutdownTimeout is returned when the shutdown callbacks are not finished
+// within the shutdown timeout.
+var ErrShutdownTimeout = errors.New("shutdown timed out")
+
+// ShutdownFunc defines the cleanup callback function which is invoked when the
+// application shuts down. The context is canceled when the shutdown timeout
+// expires.
+type ShutdownFunc func(ctx context.Context) error
+
+type shutdownHook struct {
+	name     string
+	priority int
+	fn       ShutdownFunc
+}
+
+// shutdownManager runs the registered cleanup callbacks in priority order
+// within a global timeout. The callbacks are run once per execution, on the
+// first shutdown signal or when the execution finishes, whichever comes first.
+type shutdownManager struct {
+	mu      sync.Mutex
+	hooks   []*shutdownHook
+	timeout time.Duration
+	exit    func(code int)
+
+	once sync.Once
+	done chan struct{}
+	err  error
+
+	// watch installs the signal watcher while the App is executing, it is
+	// installed once the first callback is registered, so the callbacks
+	// registered by the run function are also covered.
+	watch     func() (stop func())
+	stopWatch func()
+}
+
+func newShutdownManager() *shutdownManager {
+	return &shutdownManager{
+		timeout: defaultShutdownTimeout,
+		exit:    os.Exit,
+	}
+}
+
+// OnShutdown registers a cleanup callback with the default priority 0, it is
+// invoked when the application shuts down.
+func (a *App) OnShutdown(name string, fn ShutdownFunc) {
+	a.OnShutdownWithPriority(name, 0, fn)
+}
+
+// OnShutdownWithPriority registers a cleanup callback with the given priority.
+// The callbacks with higher priority are invoked first, the callbacks with the
+// same priority are invoked in registration order.
+func (a *App) OnShutdownWithPriority(name string, priority int, fn ShutdownFunc) {
+	a.shutdown.add(name, priority, fn)
+}
+
+func (m *shutdownManager) add(name string, priority int, fn ShutdownFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, &shutdownHook{name: name, priority: priority, fn: fn})
+	if m.watch != nil && m.stopWatch == nil {
+		m.stopWatch = m.watch()
+	}
+}
+
+// start prepares the manager for a new execution. The signal watcher is
+// installed by watch when there are callbacks registered, the returned
+// function uninstalls it.
+func (m *shutdownManager) start(watch func() (stop func())) (stop func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.once = sync.Once{}
+	m.done = make(chan struct{})
+	m.err = nil
+	m.watch = watch
+	if len(m.hooks) > 0 {
+		m.stopWatch = m.watch()
+	}
+	return func() {
+		m.mu.Lock()
+		stopWatch := m.stopWatch
+		m.watch = nil
+		m.stopWatch = nil
+		m.mu.Unlock()
+		if stopWatch != nil {
+			stopWatch()
+		}
+	}
+}
+
+// run invokes the cleanup callbacks once and waits for them to finish.
+func (m *shutdownManager) run(logger Logger) error {
+	m.once.Do(func() {
+		m.err = m.execute(logger)
+		close(m.done)
+	})
+	<-m.done
+	return m.err
+}
+
+func (m *shutdownManager) execute(logger Logger) error {
+	m.mu.Lock()
+	hooks := make([]*shutdownHook, len(m.hooks))
+	copy(hooks, m.hooks)
+	m.mu.Unlock()
+	if len(hooks) == 0 {
+		return nil
+	}
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].priority > hooks[j].priority
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	logger.Infof("%s Shutting down ...", progressMessage)
+	finished := make(chan []error, 1)
+	go func() {
+		var errs []error
+		for _, h := range hooks {
+			if ctx.Err() != nil {
+				break
+			}
+			logger.Infof("%s Running shutdown callback: %s", progressMessage, h.name)
+			if err := h.fn(ctx); err != nil {
+				logger.Errorf("%s Shutdown callback %s failed: %v", progressMessage, h.name, err)
+				errs = append(errs, fmt.Errorf("shutdown callback %s: %w", h.name, err))
+			}
+		}
+		finished <- errs
+	}()
+
+	select {
+	case errs := <-finished:
+		logger.Infof("%s Shutdown completed", progressMessage)
+		return errors.NewAggregate(errs)
+	case <-ctx.Done():
+		logger.Errorf("%s Shutdown timed out after %s", progressMessage, m.timeout)
+		return ErrShutdownTimeout
+	}
+}
+
+// watchShutdownSignals cancels the execution and runs the cleanup callbacks
+// when the first shutdown signal arrives. A second signal or the shutdown
+// timeout expiry forces the process to exit with ExitCodeForcedShutdown.
+func (a *App) watchShutdownSignals(cancel context.CancelFunc) (stop func()) {
+	var received int32
+	handler := func(sig os.Signal) {
+		if atomic.AddInt32(&received, 1) > 1 {
+			a.logger.Warnf("%s Received signal %s again, forcing exit", progressMessage, sig.String())
+			a.shutdown.exit(ExitCodeForcedShutdown)
+			return
+		}
+		a.logger.Infof("%s Received signal %s, shutting down gracefully, send it again to force exit",
+			progressMessage, sig.String())
+		cancel()
+		go func() {
+			if err := a.shutdown.run(a.logger); errors.Is(err, ErrShutdownTimeout) {
+				a.shutdown.exit(ExitCodeForcedShutdown)
+			}
+		}()
+	}
+
+	unregisters := make([]func(), 0, len(defaultShutdownSignals))
+	for _, sig := range defaultShutdownSignals {
+		unregisters = append(unregisters, a.signals.register(sig, handler))
+	}
+	return func() {
+		for _, unregister := range unregisters {
+			unregister()
+		}
+	}
+}
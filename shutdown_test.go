@@ -0,0 +1,142 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+func TestAppShutdown(t *testing.T) {
+	t.Run("should run shutdown callbacks in priority order", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		var records []string
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithRunFunc(func() error {
+				records = append(records, "run")
+				return nil
+			}),
+		)
+		for _, name := range []string{"low", "default"} {
+			name := name
+			app.OnShutdown(name, func(ctx context.Context) error {
+				records = append(records, name)
+				return nil
+			})
+		}
+		app.OnShutdownWithPriority("high", 10, func(ctx context.Context) error {
+			records = append(records, "high")
+			return nil
+		})
+		code, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.Equal(t, []string{"run", "high", "low", "default"}, records)
+		assert.Contains(t, buf.String(), "Running shutdown callback: high")
+		assert.Contains(t, buf.String(), "Shutdown completed")
+	})
+
+	t.Run("should return errors of shutdown callbacks", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		cleanupErr := errors.New("cleanup failed")
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+		)
+		app.OnShutdown("cleanup", func(ctx context.Context) error {
+			return cleanupErr
+		})
+		code, err := app.Execute(context.Background())
+		assert.ErrorIs(t, err, cleanupErr)
+		assert.Equal(t, jcli.ExitCodeError, code)
+		assert.Contains(t, buf.String(), "Shutdown callback cleanup failed")
+	})
+
+	t.Run("should return forced shutdown code on timeout", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithShutdownTimeout(time.Millisecond*100),
+		)
+		app.OnShutdown("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		code, err := app.Execute(context.Background())
+		assert.ErrorIs(t, err, jcli.ErrShutdownTimeout)
+		assert.Equal(t, jcli.ExitCodeForcedShutdown, code)
+	})
+
+	t.Run("should run shutdown callbacks registered by the run function", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		injector := jcli.NewSignalInjector()
+		registered := make(chan struct{})
+		cleaned := false
+		var app *jcli.App
+		app = jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithSignalSource(injector),
+			jcli.WithRunContextFunc(func(ctx context.Context) error {
+				app.OnShutdown("db", func(ctx context.Context) error {
+					cleaned = true
+					return nil
+				})
+				close(registered)
+				<-ctx.Done()
+				return nil
+			}),
+		)
+		go func() {
+			<-registered
+			injector.Send(syscall.SIGTERM)
+		}()
+		code, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.True(t, cleaned)
+		assert.Contains(t, buf.String(), "Received signal terminated, shutting down gracefully")
+	})
+
+	t.Run("should not log shutdown without callbacks", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(newTestLogger(&buf)),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+		)
+		code, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, jcli.ExitCodeOK, code)
+		assert.NotContains(t, buf.String(), "Shutting down")
+	})
+}
@@ -1,36 +1,231 @@
 package jcli
 
 import (
+	"context"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 )
 
 type SignalReceiver func(os.Signal)
 
+// SignalHandler defines the callback function which handles a received signal.
+// The handlers are invoked sequentially in the dispatching goroutine, a
+// long-running handler should start its own goroutine.
+type SignalHandler func(os.Signal)
+
 var defaultShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
 
-// setupSignalHandler SIGTERM and SIGINT are registered by default.
-// Register other signals via the signal parameter.
-func (a *App) setupSignalHandler(receiver SignalReceiver, signals ...os.Signal) {
-	close(a.setonce) // channel cannot be closed repeatedly, so panic occurs when called twice.
+// SignalSource abstracts the delivery of the signals, see signal.Notify and
+// signal.Stop.
+type SignalSource interface {
+	// Notify causes the source to relay the given signals to c. If no signals
+	// are provided, all signals will be relayed to c.
+	Notify(c chan<- os.Signal, sig ...os.Signal)
+	// Stop causes the source to stop relaying signals to c.
+	Stop(c chan<- os.Signal)
+}
+
+// osSignalSource is the default SignalSource which relays the incoming os
+// signals.
+type osSignalSource struct{}
+
+func (osSignalSource) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	signal.Notify(c, sig...)
+}
+
+func (osSignalSource) Stop(c chan<- os.Signal) {
+	signal.Stop(c)
+}
+
+type signalHandlerEntry struct {
+	handler SignalHandler
+}
+
+// signalRegistry dispatches the received signals to the per-signal handlers.
+// The signals are only relayed while the registry is started, stop restores
+// the default behavior of the signals, so the registry can be started again.
+type signalRegistry struct {
+	mu       sync.Mutex
+	handlers map[os.Signal][]*signalHandlerEntry
+	// source is the SignalSource set by the options, if it is nil, the
+	// source of the parent command or the App is used.
+	source SignalSource
+	// active is the SignalSource of the started registry.
+	active SignalSource
+	logger Logger
+	sigc   chan os.Signal
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newSignalRegistry() *signalRegistry {
+	return &signalRegistry{
+		handlers: make(map[os.Signal][]*signalHandlerEntry),
+	}
+}
+
+// HandleSignal registers a handler for the given signal, it returns a function
+// to unregister the handler. The handler is active while the App is running.
+func (a *App) HandleSignal(sig os.Signal, handler SignalHandler) (unregister func()) {
+	return a.signals.register(sig, handler)
+}
+
+// HandleSignal registers a handler for the given signal, it returns a function
+// to unregister the handler. The handler is active while the Command is
+// running.
+func (c *Command) HandleSignal(sig os.Signal, handler SignalHandler) (unregister func()) {
+	return c.signals.register(sig, handler)
+}
+
+// register adds a handler for the given signal. If the registry is started,
+// the signal is relayed immediately.
+func (r *signalRegistry) register(sig os.Signal, handler SignalHandler) (unregister func()) {
+	entry := &signalHandlerEntry{handler: handler}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[sig] = append(r.handlers[sig], entry)
+	if r.sigc != nil {
+		r.active.Notify(r.sigc, sig)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.unregister(sig, entry)
+		})
+	}
+}
+
+func (r *signalRegistry) unregister(sig os.Signal, entry *signalHandlerEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.handlers[sig]
+	for i, v := range entries {
+		if v == entry {
+			entries = append(entries[:i:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(entries) > 0 {
+		r.handlers[sig] = entries
+		return
+	}
+	delete(r.handlers, sig)
+
+	// stop relaying the signal which has no handlers, so that it is restored
+	// to the default behavior.
+	if r.sigc != nil {
+		r.active.Stop(r.sigc)
+		if sigs := r.registeredSignals(); len(sigs) > 0 {
+			r.active.Notify(r.sigc, sigs...)
+		}
+	}
+}
 
-	if len(signals) == 0 {
-		signals = defaultShutdownSignals
+// registeredSignals returns the signals which have handlers, the caller must
+// hold the lock.
+func (r *signalRegistry) registeredSignals() []os.Signal {
+	sigs := make([]os.Signal, 0, len(r.handlers))
+	for sig := range r.handlers {
+		sigs = append(sigs, sig)
 	}
-	a.sigc = make(chan os.Signal)
+	return sigs
+}
 
-	signal.Notify(a.sigc, signals...)
+// start starts relaying the registered signals from the source to the
+// handlers, the os signals are relayed if the source is nil. The returned
+// function stops relaying and waits for the dispatching goroutine to exit.
+func (r *signalRegistry) start(logger Logger, source SignalSource) (stop func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
+	if source == nil {
+		source = osSignalSource{}
+	}
+	sigc := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	r.sigc = sigc
+	r.quit = quit
+	r.active = source
+	r.logger = logger
+	if sigs := r.registeredSignals(); len(sigs) > 0 {
+		source.Notify(sigc, sigs...)
+	}
+
+	r.wg.Add(1)
 	go func() {
+		defer r.wg.Done()
 		for {
-			if sig, ok := <-a.sigc; ok {
-				a.logger.Debugf("%s received signal: %s", progressMessage, sig.String())
-				receiver(sig)
-			} else {
-				a.logger.Debugf("%s signal channel closed", progressMessage)
-				break
+			select {
+			case sig := <-sigc:
+				r.dispatch(sig)
+			case <-quit:
+				return
 			}
 		}
 	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			source.Stop(sigc)
+			close(quit)
+			r.sigc = nil
+			r.quit = nil
+			r.active = nil
+			r.mu.Unlock()
+			r.wg.Wait()
+		})
+	}
+}
+
+func (r *signalRegistry) dispatch(sig os.Signal) {
+	// notify the SignalInjector once the handlers complete.
+	if injected, ok := sig.(*injectedSignal); ok {
+		defer close(injected.done)
+		sig = injected.sig
+	}
+
+	r.mu.Lock()
+	entries := make([]*signalHandlerEntry, len(r.handlers[sig]))
+	copy(entries, r.handlers[sig])
+	logger := r.logger
+	r.mu.Unlock()
+
+	if logger != nil {
+		logger.Debugf("%s received signal: %s", progressMessage, sig.String())
+	}
+	for _, v := range entries {
+		v.handler(sig)
+	}
+}
+
+// notifyContext returns a copy of the parent context that is canceled when one
+// of the defaultShutdownSignals arrives. Once the context is done, the handlers
+// are unregistered, so a second signal is handled by the other handlers or
+// terminates the process.
+func (r *signalRegistry) notifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	unregisters := make([]func(), 0, len(defaultShutdownSignals))
+	for _, sig := range defaultShutdownSignals {
+		unregisters = append(unregisters, r.register(sig, func(os.Signal) {
+			cancel()
+		}))
+	}
+	go func() {
+		<-ctx.Done()
+		for _, unregister := range unregisters {
+			unregister()
+		}
+	}()
+	return ctx, cancel
 }
@@ -0,0 +1,105 @@
+package jcli
+
+import (
+	"os"
+	"sync"
+)
+
+// injectedSignal wraps a signal sent by the SignalInjector, so that the sender
+// can wait for the handlers to complete.
+type injectedSignal struct {
+	sig  os.Signal
+	done chan struct{}
+}
+
+func (s *injectedSignal) String() string {
+	return s.sig.String()
+}
+
+func (s *injectedSignal) Signal() {}
+
+type signalSubscription struct {
+	signals map[os.Signal]struct{}
+	stopped chan struct{}
+}
+
+func (s *signalSubscription) accept(sig os.Signal) bool {
+	if len(s.signals) == 0 {
+		return true
+	}
+	_, ok := s.signals[sig]
+	return ok
+}
+
+// SignalInjector is a SignalSource which relays the signals injected by Send
+// instead of the os signals. It allows tests to deliver signals synchronously
+// without sending real process signals.
+type SignalInjector struct {
+	mu   sync.Mutex
+	subs map[chan<- os.Signal]*signalSubscription
+}
+
+// NewSignalInjector creates a new SignalInjector.
+func NewSignalInjector() *SignalInjector {
+	return &SignalInjector{
+		subs: make(map[chan<- os.Signal]*signalSubscription),
+	}
+}
+
+// Notify implements the SignalSource interface.
+func (s *SignalInjector) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subs[c]
+	if !ok {
+		sub = &signalSubscription{
+			signals: make(map[os.Signal]struct{}),
+			stopped: make(chan struct{}),
+		}
+		s.subs[c] = sub
+	}
+	for _, v := range sig {
+		sub.signals[v] = struct{}{}
+	}
+}
+
+// Stop implements the SignalSource interface.
+func (s *SignalInjector) Stop(c chan<- os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub, ok := s.subs[c]; ok {
+		close(sub.stopped)
+		delete(s.subs, c)
+	}
+}
+
+// Send delivers the signal to the notified channels and waits for the
+// handlers to complete. It reports whether the signal is delivered.
+func (s *SignalInjector) Send(sig os.Signal) bool {
+	s.mu.Lock()
+	targets := make(map[chan<- os.Signal]*signalSubscription, len(s.subs))
+	for c, sub := range s.subs {
+		if sub.accept(sig) {
+			targets[c] = sub
+		}
+	}
+	s.mu.Unlock()
+
+	delivered := false
+	for c, sub := range targets {
+		injected := &injectedSignal{sig: sig, done: make(chan struct{})}
+		select {
+		case c <- injected:
+		case <-sub.stopped:
+			continue
+		}
+		select {
+		case <-injected.done:
+			delivered = true
+		case <-sub.stopped:
+		}
+	}
+	return delivered
+}
@@ -0,0 +1,226 @@
+package jcli_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipengqi/jcli"
+)
+
+type fakeSignal string
+
+func (s fakeSignal) String() string { return string(s) }
+
+func (s fakeSignal) Signal() {}
+
+func TestSignalInjector(t *testing.T) {
+	injector := jcli.NewSignalInjector()
+	assert.False(t, injector.Send(os.Interrupt))
+
+	c := make(chan os.Signal, 1)
+	injector.Notify(c, fakeSignal("reload"))
+	assert.False(t, injector.Send(os.Interrupt))
+
+	injector.Stop(c)
+	assert.False(t, injector.Send(fakeSignal("reload")))
+}
+
+func TestAppSignals(t *testing.T) {
+	t.Run("should cancel run context on shutdown signals", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		log := newTestLogger(&buf)
+		injector := jcli.NewSignalInjector()
+		running := make(chan struct{})
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(log),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithSignalSource(injector),
+			jcli.WithRunContextFunc(func(ctx context.Context) error {
+				close(running)
+				<-ctx.Done()
+				log.Infof("application canceled")
+				return nil
+			}),
+		)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = app.Execute(context.Background())
+		}()
+		<-running
+		assert.True(t, injector.Send(syscall.SIGTERM))
+		<-done
+		assert.Contains(t, buf.String(), "[info] application canceled")
+	})
+
+	t.Run("should run shutdown callbacks on shutdown signals", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var buf bytes.Buffer
+		log := newTestLogger(&buf)
+		injector := jcli.NewSignalInjector()
+		running := make(chan struct{})
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.WithLogger(log),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithSignalSource(injector),
+			jcli.WithRunContextFunc(func(ctx context.Context) error {
+				close(running)
+				<-ctx.Done()
+				return nil
+			}),
+		)
+		app.OnShutdown("cleanup", func(ctx context.Context) error {
+			return nil
+		})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = app.Execute(context.Background())
+		}()
+		<-running
+		assert.True(t, injector.Send(os.Interrupt))
+		<-done
+		assert.Contains(t, buf.String(), "shutting down gracefully")
+		assert.Contains(t, buf.String(), "Running shutdown callback: cleanup")
+	})
+
+	t.Run("should dispatch signals to the per-signal handlers", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var records []string
+		injector := jcli.NewSignalInjector()
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithSignalSource(injector),
+			jcli.WithSignalHandler(fakeSignal("reload"), func(sig os.Signal) {
+				records = append(records, "reload")
+			}),
+			jcli.WithOnSignalReceived(func(sig os.Signal) {
+				records = append(records, "receiver: "+sig.String())
+			}, fakeSignal("dump")),
+		)
+		app.AddCommands(jcli.NewCommand("sub1", "sub1 command description",
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				assert.True(t, injector.Send(fakeSignal("reload")))
+				assert.True(t, injector.Send(fakeSignal("dump")))
+				assert.False(t, injector.Send(fakeSignal("unknown")))
+				return nil
+			}),
+		))
+		os.Args = []string{"testApp", "sub1"}
+		_, err := app.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"reload", "receiver: dump"}, records)
+		assert.False(t, injector.Send(fakeSignal("reload")))
+	})
+
+	t.Run("should unregister signal handlers", func(t *testing.T) {
+		os.Args = []string{"simplecmd"}
+		var records []string
+		injector := jcli.NewSignalInjector()
+		cmd := jcli.NewCommand("simplecmd", "this is a test command",
+			jcli.WithCommandSignalSource(injector),
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				unregister := cmd.HandleSignal(fakeSignal("dump"), func(sig os.Signal) {
+					records = append(records, "dump")
+				})
+				assert.True(t, injector.Send(fakeSignal("dump")))
+				unregister()
+				assert.False(t, injector.Send(fakeSignal("dump")))
+				return nil
+			}),
+		)
+		_, err := cmd.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dump"}, records)
+	})
+
+	t.Run("should dispatch signals to the handlers of the parent commands", func(t *testing.T) {
+		var records []string
+		injector := jcli.NewSignalInjector()
+		root := jcli.NewCommand("simplecmd", "this is a test command",
+			jcli.WithCommandSignalSource(injector),
+			jcli.WithCommandSignalHandler(syscall.SIGHUP, func(sig os.Signal) {
+				records = append(records, "root: "+sig.String())
+			}),
+		)
+		root.AddCommands(jcli.NewCommand("sub1", "sub1 command description",
+			jcli.WithCommandRunFunc(func(cmd *jcli.Command, args []string) error {
+				assert.True(t, injector.Send(syscall.SIGHUP))
+				return nil
+			}),
+		))
+		root.CobraCommand().SetArgs([]string{"sub1"})
+		_, err := root.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"root: hangup"}, records)
+		assert.False(t, injector.Send(syscall.SIGHUP))
+	})
+
+	t.Run("sub commands should inherit the signal source", func(t *testing.T) {
+		injector := jcli.NewSignalInjector()
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithSignalSource(injector),
+		)
+		running := make(chan struct{})
+		app.AddCommands(jcli.NewCommand("sub1", "sub1 command description",
+			jcli.WithCommandRunContextFunc(func(ctx context.Context, cmd *jcli.Command, args []string) error {
+				close(running)
+				<-ctx.Done()
+				return ctx.Err()
+			}),
+		))
+		app.Command().SetArgs([]string{"sub1"})
+		done := make(chan error)
+		go func() {
+			_, err := app.Execute(context.Background())
+			done <- err
+		}()
+		<-running
+		assert.True(t, injector.Send(os.Interrupt))
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("should run the App repeatedly", func(t *testing.T) {
+		os.Args = []string{"testApp"}
+		var count int
+		injector := jcli.NewSignalInjector()
+		app := jcli.New("simple",
+			jcli.WithBaseName("testApp"),
+			jcli.EnableSilence(),
+			jcli.DisableConfig(),
+			jcli.DisableVersion(),
+			jcli.WithSignalSource(injector),
+			jcli.WithOnSignalReceived(func(signal os.Signal) {
+				count++
+			}),
+			jcli.WithRunFunc(func() error {
+				injector.Send(os.Interrupt)
+				return nil
+			}),
+		)
+		assert.NotPanics(t, func() {
+			_, _ = app.Execute(context.Background())
+			_, _ = app.Execute(context.Background())
+		})
+		assert.Equal(t, 2, count)
+	})
+}